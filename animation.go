@@ -0,0 +1,96 @@
+package main
+
+// The board logic always updates straight away, so none of these animations
+// ever hold up input. They only put off when a tile's new picture is shown.
+
+const sparksPerMine = 6
+
+func animate() bool {
+	return !settings.ReducedMotion
+}
+
+// ripple delays showing tiles which were just uncovered so that an opened
+// region spreads out from the tiles that were clicked, one ring per frame.
+func (g *Grid) ripple(origins []int, uncovered []int) {
+	if !animate() || len(uncovered) == 0 {
+		return
+	}
+
+	opened := make(map[int]bool, len(uncovered))
+	for _, pos := range uncovered {
+		opened[pos] = true
+	}
+
+	dist := make(map[int]int, len(uncovered))
+	queue := []int{}
+	for _, pos := range origins {
+		if opened[pos] {
+			dist[pos] = 0
+			queue = append(queue, pos)
+		}
+	}
+
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, n := range g.Neighbours(pos) {
+			if _, seen := dist[n]; seen || !opened[n] {
+				continue
+			}
+			dist[n] = dist[pos] + 1
+			queue = append(queue, n)
+		}
+	}
+
+	for pos, d := range dist {
+		if d == 0 {
+			continue
+		}
+		t := g.Tiles[pos]
+		t.SetTile(TILE_COVERED)
+		t.RevealIn = d
+		t.RevealAs = TileType(t.BombCount)
+	}
+}
+
+// coveredTiles lists the positions of every covered tile so that the newly
+// uncovered ones can be found after a move.
+func (g *Grid) coveredTiles() []int {
+	covered := []int{}
+	for cnt, t := range g.Tiles {
+		if t.Covered {
+			covered = append(covered, cnt)
+		}
+	}
+	return covered
+}
+
+func (g *Grid) uncoveredSince(covered []int) []int {
+	uncovered := []int{}
+	for _, pos := range covered {
+		if !g.Tiles[pos].Covered {
+			uncovered = append(uncovered, pos)
+		}
+	}
+	return uncovered
+}
+
+// Burst sends out a shower of sparks from a mine as it goes off.
+func (g *Grid) Burst(x, y int) {
+	if !animate() {
+		return
+	}
+	for cnt := 0; cnt < sparksPerMine; cnt++ {
+		s := NewBurstSpark(x, y)
+		g.Sparks = append(g.Sparks, s)
+		allSprites.Sprites = append(allSprites.Sprites, s)
+	}
+}
+
+// clearSparks removes the sparks from the last game.
+func (g *Grid) clearSparks() {
+	for _, s := range g.Sparks {
+		allSprites.Remove(s)
+	}
+	g.Sparks = nil
+}
@@ -126,3 +126,65 @@ BRBBBBBR
 BRRBBBRR
 BRRRBRRR
 BRRRRRRR`
+
+const tileMine = `BBBBBBBB
+Bxxxxxxx
+BxxxBxxx
+BxxBBBxx
+BxBwBBBx
+BxBBBBBx
+BxxBBBxx
+BxxxBxxx`
+
+const tileMineWrong = `BBBBBBBB
+Bxxxxxxx
+BrxxBxrx
+BxrBBrxx
+BxBrrBBx
+BxBrrBBx
+BxrBBrxx
+Brxxxxrx`
+
+const faceNormal = `  XXXXXX
+ XyyyyyyX
+XyyyyyyyyX
+XyyByyByyX
+XyyByyByyX
+XyyyyyyyyX
+XyByyyyByX
+XyyBBBByyX
+ XyyyyyyX
+  XXXXXX`
+
+const faceOoh = `  XXXXXX
+ XyyyyyyX
+XyBByyBByX
+XyBByyBByX
+XyyyyyyyyX
+XyyyBByyyX
+XyyByyByyX
+XyyyBByyyX
+ XyyyyyyX
+  XXXXXX`
+
+const faceDead = `  XXXXXX
+ XyyyyyyX
+XyByByByBX
+XyyByyyByX
+XyByByByBX
+XyyyyyyyyX
+XyyBBBByyX
+XyByyyyByX
+ XyyyyyyX
+  XXXXXX`
+
+const faceCool = `  XXXXXX
+ XyyyyyyX
+XyyyyyyyyX
+XBBBBBBBBX
+XyBBByBBBX
+XyyyyyyyyX
+XyByyyyByX
+XyyBBBByyX
+ XyyyyyyX
+  XXXXXX`
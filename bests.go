@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Personal bests are the fastest ranked win without hints for each
+// difficulty and board size, kept in milliseconds. They're an index over the
+// scores file so the best time doesn't depend on how much history is kept,
+// and the two are checked together in case either was lost.
+type Bests map[string]int64
+
+func bestsKey(g *Grid) string {
+	return fmt.Sprintf("%s %dx%d", g.Difficulty, g.Width, g.Height)
+}
+
+func bestsPath() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "bests.json"), nil
+}
+
+func readBests(fn string) (Bests, error) {
+	b := Bests{}
+	data, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return b, nil
+}
+
+// recordBest checks a ranked win without hints against the personal best
+// kept under key, and keeps it if it's faster. fromScores is the best time
+// found in the scores file, or 0 if there isn't one. It returns the best time
+// before this game, and whether this one beat it.
+func recordBest(key string, elapsed, fromScores time.Duration) (time.Duration, bool) {
+	fn, err := bestsPath()
+	if err != nil {
+		return 0, false
+	}
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return 0, false
+	}
+	defer unlock()
+	b, err := readBests(fn)
+	if err != nil {
+		return 0, false
+	}
+
+	best := fromScores
+	if ms, ok := b[key]; ok {
+		if d := time.Duration(ms) * time.Millisecond; best == 0 || d < best {
+			best = d
+		}
+	}
+	if best != 0 && elapsed >= best {
+		return best, false
+	}
+	b[key] = elapsed.Milliseconds()
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return best, true
+	}
+	writeFileAtomic(fn, data)
+	return best, true
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mines and safe tiles in a plain text board. The optional second layer uses
+// the same cells as a save game.
+const (
+	BOARD_MINE = '*'
+	BOARD_SAFE = '.'
+)
+
+// MBF is the Minesweeper Arbiter board format: a byte each for the width and
+// height, two bytes for the number of mines, then the x and y of each mine.
+const (
+	MBF_EXT       = ".mbf"
+	MBF_MAX_SIDE  = 255
+	MBF_MAX_MINES = 65535
+)
+
+// A Board is a layout of mines read from or written to a file, along with how
+// far the game had got if the file says. Cells is nil if it doesn't.
+type Board struct {
+	Width  int
+	Height int
+	Mines  []int
+	Cells  []string
+}
+
+// NewBoard takes the board from a game once its mines have been laid.
+func NewBoard(g *Grid) (*Board, error) {
+	if g.State != GAME_RUNNING && g.State != GAME_OVER {
+		return nil, errors.New("the mines haven't been laid yet")
+	}
+	sg := NewSaveGame(g)
+	return &Board{Width: sg.Width, Height: sg.Height, Mines: sg.Mines, Cells: sg.Board}, nil
+}
+
+// check makes sure the board is one which can be played.
+func (b *Board) check() error {
+	n := b.Width * b.Height
+	switch {
+	case len(b.Mines) == 0:
+		return errors.New("board has no mines")
+	case len(b.Mines) >= n:
+		return fmt.Errorf("board has %d mines but only %d tiles", len(b.Mines), n)
+	}
+	return nil
+}
+
+// Fits checks that the board will fit in the terminal.
+func (b *Board) Fits() bool {
+	return Width/TILE_WIDTH >= b.Width && (Height-HEADER_OFFSET)/TILE_HEIGHT >= b.Height
+}
+
+// Text writes the board out with a line for each row, and the opened and
+// flagged tiles underneath after a blank line.
+func (b *Board) Text() []byte {
+	mines := make([]bool, b.Width*b.Height)
+	for _, m := range b.Mines {
+		mines[m] = true
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, BOARD_HEADER+"\n", b.Width, b.Height, len(b.Mines))
+	for r := 0; r < b.Height; r++ {
+		row := make([]byte, b.Width)
+		for c := range row {
+			row[c] = BOARD_SAFE
+			if mines[r*b.Width+c] {
+				row[c] = BOARD_MINE
+			}
+		}
+		buf.Write(row)
+		buf.WriteByte('\n')
+	}
+	if b.Cells != nil {
+		buf.WriteByte('\n')
+		for _, row := range b.Cells {
+			buf.WriteString(row)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// The header line written at the top of a text board.
+const BOARD_HEADER = "# bombitron board %dx%d, %d mines"
+
+// ParseTextBoard reads a plain text board. Lines starting with # before the
+// board are comments; after that # is a covered tile in the second layer. If
+// the header bombitron writes is there, the board has to match it.
+func ParseTextBoard(data []byte, name string) (*Board, error) {
+	var layers [][]string
+	var first []int
+	var rows []string
+	start := 0
+	var header []int
+	headerLine := 0
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for s.Scan() {
+		line++
+		l := strings.TrimRight(s.Text(), " \t\r")
+		switch {
+		case len(layers) == 0 && rows == nil && strings.HasPrefix(l, "#"):
+			var w, h, n int
+			if _, err := fmt.Sscanf(l, BOARD_HEADER, &w, &h, &n); err == nil && header == nil {
+				header = []int{w, h, n}
+				headerLine = line
+			}
+			continue
+		case l == "":
+			if rows != nil {
+				layers = append(layers, rows)
+				first = append(first, start)
+				rows = nil
+			}
+			continue
+		}
+		if rows == nil {
+			start = line
+		}
+		rows = append(rows, l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if rows != nil {
+		layers = append(layers, rows)
+		first = append(first, start)
+	}
+
+	switch {
+	case len(layers) == 0:
+		return nil, fmt.Errorf("%s: no board in the file", name)
+	case len(layers) > 2:
+		return nil, fmt.Errorf("%s:%d: only the mines and one layer of tiles are allowed", name, first[2])
+	}
+
+	b := &Board{Width: len(layers[0][0]), Height: len(layers[0])}
+	for r, row := range layers[0] {
+		line := first[0] + r
+		if len(row) != b.Width {
+			return nil, fmt.Errorf("%s:%d: row is %d tiles wide, expected %d", name, line, len(row), b.Width)
+		}
+		for c, ch := range []byte(row) {
+			switch ch {
+			case BOARD_MINE:
+				b.Mines = append(b.Mines, r*b.Width+c)
+			case BOARD_SAFE:
+			default:
+				return nil, fmt.Errorf("%s:%d: unexpected %q in column %d, expected %q or %q", name, line, ch, c+1, BOARD_SAFE, BOARD_MINE)
+			}
+		}
+	}
+
+	if len(layers) == 2 {
+		cells := layers[1]
+		if len(cells) != b.Height {
+			return nil, fmt.Errorf("%s:%d: tile layer has %d rows, expected %d", name, first[1], len(cells), b.Height)
+		}
+		flags := 0
+		for r, row := range cells {
+			line := first[1] + r
+			if len(row) != b.Width {
+				return nil, fmt.Errorf("%s:%d: row is %d tiles wide, expected %d", name, line, len(row), b.Width)
+			}
+			for c, ch := range []byte(row) {
+				switch ch {
+				case SAVE_COVERED, SAVE_QUESTION:
+				case SAVE_FLAG:
+					flags++
+					if flags > len(b.Mines) {
+						return nil, fmt.Errorf("%s:%d: more flags than the %d mines on the board", name, line, len(b.Mines))
+					}
+				case SAVE_REVEALED:
+					if layers[0][r][c] == BOARD_MINE {
+						return nil, fmt.Errorf("%s:%d: tile in column %d is opened but has a mine", name, line, c+1)
+					}
+				default:
+					return nil, fmt.Errorf("%s:%d: unexpected %q in column %d", name, line, ch, c+1)
+				}
+			}
+		}
+		b.Cells = cells
+	}
+
+	if header != nil && (header[0] != b.Width || header[1] != b.Height || header[2] != len(b.Mines)) {
+		return nil, fmt.Errorf("%s:%d: header says %dx%d with %d mines, but the board is %dx%d with %d",
+			name, headerLine, header[0], header[1], header[2], b.Width, b.Height, len(b.Mines))
+	}
+	if err := b.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return b, nil
+}
+
+// MBF writes the board in the Minesweeper Arbiter format. It only holds the
+// mines, so how far the game had got is lost.
+func (b *Board) MBF() ([]byte, error) {
+	if b.Width > MBF_MAX_SIDE || b.Height > MBF_MAX_SIDE || len(b.Mines) > MBF_MAX_MINES {
+		return nil, fmt.Errorf("a %dx%d board with %d mines is too big for an mbf file", b.Width, b.Height, len(b.Mines))
+	}
+	data := []byte{byte(b.Width), byte(b.Height), 0, 0}
+	binary.BigEndian.PutUint16(data[2:], uint16(len(b.Mines)))
+	for _, m := range b.Mines {
+		data = append(data, byte(m%b.Width), byte(m/b.Width))
+	}
+	return data, nil
+}
+
+// ParseMBF reads a Minesweeper Arbiter board. Since it's not text, errors
+// give the offset into the file instead of a line.
+func ParseMBF(data []byte, name string) (*Board, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%s: too short for an mbf file", name)
+	}
+	b := &Board{Width: int(data[0]), Height: int(data[1])}
+	if b.Width == 0 || b.Height == 0 {
+		return nil, fmt.Errorf("%s: byte 0: invalid board size %dx%d", name, b.Width, b.Height)
+	}
+	mines := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) != 4+2*mines {
+		return nil, fmt.Errorf("%s: byte 2: file says %d mines but has room for %d", name, mines, (len(data)-4)/2)
+	}
+
+	taken := make([]bool, b.Width*b.Height)
+	for off := 4; off < len(data); off += 2 {
+		x, y := int(data[off]), int(data[off+1])
+		if x >= b.Width || y >= b.Height {
+			return nil, fmt.Errorf("%s: byte %d: mine at %d,%d is off the %dx%d board", name, off, x, y, b.Width, b.Height)
+		}
+		pos := y*b.Width + x
+		if taken[pos] {
+			return nil, fmt.Errorf("%s: byte %d: there's already a mine at %d,%d", name, off, x, y)
+		}
+		taken[pos] = true
+		b.Mines = append(b.Mines, pos)
+	}
+
+	if err := b.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return b, nil
+}
+
+// LoadBoard reads a board, picking the format from the file name.
+func LoadBoard(fn string) (*Board, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(fn), MBF_EXT) {
+		return ParseMBF(data, fn)
+	}
+	return ParseTextBoard(data, fn)
+}
+
+// WriteBoard writes a board, picking the format from the file name.
+func WriteBoard(fn string, b *Board) error {
+	data := b.Text()
+	if strings.EqualFold(filepath.Ext(fn), MBF_EXT) {
+		var err error
+		if data, err = b.MBF(); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(fn, data)
+}
+
+// StartBoard sets up a board from a file. If the file has the tiles as well,
+// the game carries on from there, otherwise the mines are laid on the first
+// reveal like a daily board.
+func (g *Grid) StartBoard(b *Board) error {
+	g.Reset()
+	if b.Cells != nil {
+		err := g.Restore(&SaveGame{
+			Width:      b.Width,
+			Height:     b.Height,
+			TotalBombs: len(b.Mines),
+			Difficulty: "custom",
+			Rules:      g.Rules,
+			Mines:      b.Mines,
+			Board:      b.Cells,
+		})
+		if err != nil {
+			return err
+		}
+		g.Record = settings.RecordReplays
+		return nil
+	}
+
+	g.SetSize(b.Width, b.Height)
+	g.Difficulty = "custom"
+	g.Seed = 0
+	g.TotalBombs = len(b.Mines)
+	g.Layout = b.Mines
+	g.Record = settings.RecordReplays
+	g.State = GAME_STARTED
+	return nil
+}
+
+// boardsPath picks a new file in the boards directory. The file is created
+// straight away so that two boards saved at the same moment get a name each.
+func boardsPath() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	d = filepath.Join(d, "boards")
+	if err := os.MkdirAll(d, 0755); err != nil {
+		return "", err
+	}
+	f, err := createNewFile(d, "board-"+gameClock.Now().Format("20060102-150405.000"), ".txt")
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// ExportBoard saves the board being played into the boards directory, and
+// returns the file it was saved to. The name is printed again on the way out.
+func (g *Grid) ExportBoard() (string, error) {
+	b, err := NewBoard(g)
+	if err != nil {
+		return "", err
+	}
+	fn, err := boardsPath()
+	if err != nil {
+		return "", err
+	}
+	if err := WriteBoard(fn, b); err != nil {
+		return "", err
+	}
+	setExitMessage("board", "board saved to "+fn)
+	return fn, nil
+}
+
+// runConvert converts a board from one format to another.
+func runConvert(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: bombitron convert <from> <to>")
+		return 1
+	}
+	b, err := LoadBoard(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := WriteBoard(args[1], b); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseTextBoard(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		w, h  int
+		mines []int
+		cells bool
+		want  string
+	}{
+		{"mines only", "..*\n*..\n", 3, 2, []int{2, 3}, false, ""},
+		{"comments", "# a board\n# by me\n..*\n*..\n", 3, 2, []int{2, 3}, false, ""},
+		{"header", "# bombitron board 3x2, 2 mines\n..*\n*..\n", 3, 2, []int{2, 3}, false, ""},
+		{"tile layer", "..*\n*..\n\n#.F\n#.#\n", 3, 2, []int{2, 3}, true, ""},
+		{"trailing spaces", "..* \n*..\r\n", 3, 2, []int{2, 3}, false, ""},
+		{"header size is wrong", "# bombitron board 4x2, 2 mines\n..*\n*..\n", 0, 0, nil, false, ":1: header says 4x2"},
+		{"header mines are wrong", "# bombitron board 3x2, 1 mines\n..*\n*..\n", 0, 0, nil, false, "with 1 mines"},
+		{"empty", "# nothing\n", 0, 0, nil, false, "no board"},
+		{"ragged", "..*\n*.\n", 0, 0, nil, false, ":2: row is 2 tiles wide"},
+		{"bad character", "..*\n*x.\n", 0, 0, nil, false, "unexpected 'x'"},
+		{"no mines", "...\n...\n", 0, 0, nil, false, "no mines"},
+		{"all mines", "**\n**\n", 0, 0, nil, false, "only 4 tiles"},
+		{"short tile layer", "..*\n*..\n\n#.F\n", 0, 0, nil, false, "tile layer has 1 rows"},
+		{"opened mine", "..*\n*..\n\n#..\n#.#\n", 0, 0, nil, false, ":4: tile in column 3 is opened"},
+		{"too many flags", "..*\n*..\n\nF#F\n#F#\n", 0, 0, nil, false, ":5: more flags than the 2 mines"},
+		{"bad tile", "..*\n*..\n\n#.F\n#x#\n", 0, 0, nil, false, "unexpected 'x'"},
+		{"three layers", "..*\n*..\n\n###\n###\n\n###\n###\n", 0, 0, nil, false, ":7: only the mines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ParseTextBoard([]byte(tt.text), "test.txt")
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("ParseTextBoard() = %v, want an error containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTextBoard() = %v", err)
+			}
+			if b.Width != tt.w || b.Height != tt.h {
+				t.Errorf("board is %dx%d, want %dx%d", b.Width, b.Height, tt.w, tt.h)
+			}
+			if len(b.Mines) != len(tt.mines) {
+				t.Fatalf("mines = %v, want %v", b.Mines, tt.mines)
+			}
+			for cnt, m := range tt.mines {
+				if b.Mines[cnt] != m {
+					t.Errorf("mines = %v, want %v", b.Mines, tt.mines)
+					break
+				}
+			}
+			if (b.Cells != nil) != tt.cells {
+				t.Errorf("cells = %v, want a tile layer: %v", b.Cells, tt.cells)
+			}
+		})
+	}
+}
+
+func TestParseMBF(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		mines []int
+		want  string
+	}{
+		{"two mines", []byte{3, 2, 0, 2, 2, 0, 0, 1}, []int{2, 3}, ""},
+		{"too short", []byte{3, 2, 0}, nil, "too short"},
+		{"no size", []byte{0, 2, 0, 0}, nil, "byte 0: invalid board size"},
+		{"count is wrong", []byte{3, 2, 0, 2, 2, 0}, nil, "byte 2: file says 2 mines"},
+		{"off the board", []byte{3, 2, 0, 1, 3, 0}, nil, "byte 4: mine at 3,0 is off"},
+		{"twice", []byte{3, 2, 0, 2, 1, 1, 1, 1}, nil, "byte 6: there's already a mine"},
+		{"no mines", []byte{3, 2, 0, 0}, nil, "no mines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ParseMBF(tt.data, "test.mbf")
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("ParseMBF() = %v, want an error containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMBF() = %v", err)
+			}
+			if len(b.Mines) != len(tt.mines) || b.Mines[0] != tt.mines[0] || b.Mines[1] != tt.mines[1] {
+				t.Errorf("mines = %v, want %v", b.Mines, tt.mines)
+			}
+		})
+	}
+}
+
+func TestBoardRoundTrip(t *testing.T) {
+	tests := []*Board{
+		{Width: 3, Height: 2, Mines: []int{2, 3}},
+		{Width: 3, Height: 2, Mines: []int{2, 3}, Cells: []string{"#.F", "#.#"}},
+		{Width: 30, Height: 16, Mines: []int{0, 17, 479}},
+	}
+
+	for _, b := range tests {
+		got, err := ParseTextBoard(b.Text(), "test.txt")
+		if err != nil {
+			t.Fatalf("ParseTextBoard(Text()) = %v", err)
+		}
+		if !bytes.Equal(got.Text(), b.Text()) {
+			t.Errorf("text board changed:\n%s\nwant\n%s", got.Text(), b.Text())
+		}
+
+		data, err := b.MBF()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = ParseMBF(data, "test.mbf")
+		if err != nil {
+			t.Fatalf("ParseMBF(MBF()) = %v", err)
+		}
+		if got.Cells != nil || got.Width != b.Width || got.Height != b.Height || len(got.Mines) != len(b.Mines) {
+			t.Errorf("mbf board = %+v, want %+v without tiles", got, b)
+		}
+	}
+
+	big := &Board{Width: 256, Height: 2, Mines: []int{0}}
+	if _, err := big.MBF(); err == nil {
+		t.Errorf("MBF() of a 256 wide board worked, want an error")
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"math"
 	"math/rand"
 	"time"
 
@@ -12,7 +11,7 @@ import (
 const (
 	TILE_WIDTH               = 8
 	TILE_HEIGHT              = 8
-	HEADER_OFFSET            = 10
+	HEADER_OFFSET            = 18
 	EASY_BOMB_RATE   float64 = 0.12345
 	MEDIUM_BOMB_RATE float64 = 0.15625
 	HARD_BOMB_RATE   float64 = 0.20625
@@ -35,8 +34,21 @@ const (
 	TILE_FLAG
 	TILE_BOMB
 	TILE_QUESTION
+	TILE_MINE
+	TILE_MINE_WRONG
 )
 
+// Rules are the options a game was played with.
+type Rules struct {
+	SafeFirstClick bool `json:"safe_first_click"`
+	QuestionMarks  bool `json:"question_marks"`
+}
+
+var DefaultRules = Rules{
+	SafeFirstClick: true,
+	QuestionMarks:  true,
+}
+
 type Tile struct {
 	sprite.BaseSprite
 	GridX        int
@@ -48,6 +60,9 @@ type Tile struct {
 	HaveFlag     bool
 	HaveQuestion bool
 	Covered      bool
+	Exploded     bool
+	RevealIn     int
+	RevealAs     TileType
 }
 
 type Background struct {
@@ -57,15 +72,59 @@ type Background struct {
 type Grid struct {
 	State          GameState
 	BombRate       float64
+	Difficulty     string
+	Daily          string
+	Puzzle         string
+	Start          int
+	Seed           int64
+	Rules          Rules
+	Paused         bool
+	Record         bool
+	ReplayFile     string
+	Analysis       bool
+	Assisted       bool
+	Scored         bool
+	Code           bool
+	Hints          int
+	Guesses        []Guess
+	History        History
 	Width          int
 	Height         int
 	Tiles          []*Tile
 	TotalBombs     int
 	FlagsRemaining *FlagsRemainingText
 	TimerElapsed   *TimerElapsedText
-	Super          *SuperText
+	Summary        *Summary
 	Background     *Background
 	Kaboom         *Kaboom
+	Result         *Result
+	Moves          []Move
+	Layout         []int
+	StartCells     []string
+	AnalysisText   *AnalysisText
+	Hover          *Hover
+	Face           *StatusFace
+	HUD            *HUD
+	HintBox        *HintBox
+	Overlay        *Overlay
+	Marker         *StartMarker
+	Sparks         []*Spark
+}
+
+// Result holds the outcome of a finished game. Assisted games used undo, so
+// they're kept out of any records. Hinted games are kept apart from the rest.
+// Best and NewBest are filled in once the score has been written, and Unsaved
+// lists the records which couldn't be.
+type Result struct {
+	Won        bool
+	Assisted   bool
+	Hints      int
+	Elapsed    time.Duration
+	FinishedAt time.Time
+	Best       time.Duration
+	NewBest    bool
+	Unsaved    []string
+	saving     []<-chan recordSave
 }
 
 type FlagsRemainingText struct {
@@ -77,14 +136,12 @@ type FlagsRemainingText struct {
 type TimerElapsedText struct {
 	sprite.BaseSprite
 	font      *sprite.Font
-	Started   bool
-	StartTime time.Time
+	Watch     *Stopwatch
+	Precision int
 }
 
-type SuperText struct {
+type AnalysisText struct {
 	sprite.BaseSprite
-	TargetY int
-	VY      float64
 }
 
 type Kaboom struct {
@@ -109,6 +166,10 @@ func NewFlagsRemaining() *FlagsRemainingText {
 		f.UpdateText()
 	})
 
+	f.RegisterEvent("NewGame", func() {
+		f.Visible = false
+	})
+
 	return f
 }
 
@@ -123,74 +184,61 @@ func NewTimerElapsed() *TimerElapsedText {
 		X:       20,
 		Y:       1,
 		Visible: false},
-		font: sprite.NewPakuFont(),
+		font:      sprite.NewPakuFont(),
+		Watch:     NewStopwatch(gameClock),
+		Precision: settings.TimerPrecision,
 	}
 	t.Init()
 
 	t.RegisterEvent("StartTimer", func() {
 		t.Visible = true
-		t.Started = true
-		t.StartTime = time.Now()
 	})
 
 	t.RegisterEvent("UpdateTimer", func() {
-		t.UpdateText()
+		if t.Watch.Running() {
+			t.UpdateText()
+		}
 	})
 
-	t.RegisterEvent("Explode", func() {
-		t.Started = false
+	t.RegisterEvent("GameOver", func() {
+		t.UpdateText()
 	})
 
-	t.RegisterEvent("GameOver", func() {
-		t.Started = false
+	t.RegisterEvent("NewGame", func() {
+		t.Visible = false
 	})
 
 	return t
 }
 
 func (t *TimerElapsedText) UpdateText() {
-	if t.Started {
-		s := fmt.Sprintf("%d", int(time.Since(t.StartTime).Seconds()))
-		surf := sprite.NewSurfaceFromString(t.font.BuildString(s), true)
-		t.BlockCostumes = []*sprite.Surface{&surf}
-		t.X = Width - surf.Width - 4
-	}
+	s := formatElapsed(t.Watch.Elapsed(), t.Precision)
+	surf := sprite.NewSurfaceFromString(t.font.BuildString(s), true)
+	t.BlockCostumes = []*sprite.Surface{&surf}
+	t.X = Width - surf.Width - 4
 }
 
-func NewSuperText() *SuperText {
-	s := &SuperText{BaseSprite: sprite.BaseSprite{
+func NewAnalysisText() *AnalysisText {
+	a := &AnalysisText{BaseSprite: sprite.BaseSprite{
+		X:       20,
+		Y:       1,
 		Visible: false},
 	}
-	s.Init()
+	a.Init()
 
-	surf := sprite.NewSurfaceFromPng("super.png", true)
-	s.BlockCostumes = append(s.BlockCostumes, &surf)
+	f := sprite.NewPakuFont()
+	surf := sprite.NewSurfaceFromString(f.BuildString("analysis"), true)
+	a.BlockCostumes = []*sprite.Surface{&surf}
 
-	s.X = Width/2 - surf.Width/2
-	s.Y = -s.Height
-	s.TargetY = Height/2 - surf.Height/2
-
-	s.RegisterEvent("resizeScreen", func() {
-		s.X = Width/2 - surf.Width/2
-		s.Y = -s.Height
-		s.TargetY = Height/2 - surf.Height/2
+	a.RegisterEvent("ShowAnalysis", func() {
+		a.Visible = true
 	})
 
-	s.RegisterEvent("GameWon", func() {
-		allSprites.MoveToTop(s)
-		s.Visible = true
+	a.RegisterEvent("NewGame", func() {
+		a.Visible = false
 	})
 
-	return s
-}
-
-func (s *SuperText) Update() {
-	if !s.Visible || s.Y == s.TargetY {
-		return
-	}
-
-	s.VY = (float64(s.TargetY) - float64(s.Y)) * 0.3
-	s.Y += int(math.Round(s.VY))
+	return a
 }
 
 func NewKaboom() *Kaboom {
@@ -218,13 +266,35 @@ func NewKaboom() *Kaboom {
 		k.Y = Height/2 - surf1.Height/2
 	})
 
+	k.RegisterEvent("NewGame", func() {
+		k.Visible = false
+		k.Timer = 0
+		k.SetCostume(0)
+	})
+
+	k.RegisterEvent("ReturnToGrid", func() {
+		k.Visible = false
+	})
+
+	k.RegisterEvent("Undo", func() {
+		k.Visible = false
+		k.Timer = 0
+		k.SetCostume(0)
+	})
+
 	return k
 }
 
+// Update plays the explosion and then gets out of the way. With reduced
+// motion it goes straight to the last picture and stays up as a banner.
 func (k *Kaboom) Update() {
 	if !k.Visible {
 		return
 	}
+	if !animate() {
+		k.SetCostume(len(k.BlockCostumes) - 1)
+		return
+	}
 	k.Timer++
 
 	if k.Timer > k.TimeOut {
@@ -265,11 +335,19 @@ func NewTile() *Tile {
 	t.Init()
 
 	t.RegisterEvent("GameWon", func() {
-		if t.HaveFlag == true {
+		if t.HaveFlag == true && animate() {
 			t.VX, t.VY = randVec()
 		}
 	})
 
+	t.RegisterEvent("Pause", func() {
+		t.Visible = false
+	})
+
+	t.RegisterEvent("Unpause", func() {
+		t.Visible = true
+	})
+
 	t.RegisterEvent("ReturnToGrid", func() {
 		t.VX = 0
 		t.VY = 0
@@ -302,7 +380,10 @@ func (t *Tile) RevealTile() {
 	if t.HaveFlag || t.HaveQuestion {
 		return
 	} else if t.HaveBomb {
+		t.Exploded = true
 		t.SetTile(TILE_BOMB)
+		gameGrid.Finish(false)
+		gameGrid.RevealMines(gameGrid.GetTilePos(t), animate())
 		allSprites.TriggerEvent("Explode")
 		allSprites.TriggerEvent("GameOver")
 		gameGrid.State = GAME_OVER
@@ -320,8 +401,12 @@ func (t *Tile) SetFlag() {
 	if t.HaveFlag {
 		gameGrid.FlagsRemaining.Remaining += 1
 		t.HaveFlag = false
-		t.HaveQuestion = true
-		t.SetTile(TILE_QUESTION)
+		if gameGrid.Rules.QuestionMarks {
+			t.HaveQuestion = true
+			t.SetTile(TILE_QUESTION)
+		} else {
+			t.SetTile(TILE_COVERED)
+		}
 		allSprites.TriggerEvent("ShowFlagsRemaining")
 	} else if t.HaveQuestion {
 		t.HaveQuestion = false
@@ -336,6 +421,22 @@ func (t *Tile) SetFlag() {
 	}
 }
 
+// Redraw shows the picture which matches the tile's current state.
+func (t *Tile) Redraw() {
+	switch {
+	case t.Exploded:
+		t.SetTile(TILE_BOMB)
+	case !t.Covered:
+		t.SetTile(TileType(t.BombCount))
+	case t.HaveFlag:
+		t.SetTile(TILE_FLAG)
+	case t.HaveQuestion:
+		t.SetTile(TILE_QUESTION)
+	default:
+		t.SetTile(TILE_COVERED)
+	}
+}
+
 func (t *Tile) SetTile(v TileType) {
 	tileImages := []string{
 		tileEmpty,
@@ -352,6 +453,8 @@ func (t *Tile) SetTile(v TileType) {
 		tileFlag,
 		tileBomb,
 		tileQuestion,
+		tileMine,
+		tileMineWrong,
 	}
 	surf := sprite.NewSurfaceFromString(tileImages[v], false)
 	t.BlockCostumes = []*sprite.Surface{&surf}
@@ -359,6 +462,16 @@ func (t *Tile) SetTile(v TileType) {
 }
 
 func (t *Tile) Update() {
+	if t.RevealIn > 0 {
+		t.RevealIn--
+		if t.RevealIn == 0 {
+			t.SetTile(t.RevealAs)
+			if t.RevealAs == TILE_MINE {
+				gameGrid.Burst(t.X+TILE_WIDTH/2, t.Y+TILE_HEIGHT/2)
+			}
+		}
+	}
+
 	if gameGrid.State != GAME_OVER {
 		return
 	}
@@ -382,11 +495,20 @@ func (t *Tile) Update() {
 func NewGrid() *Grid {
 	g := &Grid{
 		State:          GAME_INIT,
+		Start:          -1,
+		Rules:          DefaultRules,
 		FlagsRemaining: NewFlagsRemaining(),
 		TimerElapsed:   NewTimerElapsed(),
-		Super:          NewSuperText(),
+		Summary:        NewSummary(),
 		Background:     NewBackground(),
 		Kaboom:         NewKaboom(),
+		AnalysisText:   NewAnalysisText(),
+		Hover:          NewHover(),
+		Face:           NewStatusFace(),
+		HUD:            NewHUD(),
+		HintBox:        NewHintBox(),
+		Overlay:        NewOverlay(),
+		Marker:         NewStartMarker(),
 	}
 	return g
 }
@@ -398,19 +520,31 @@ func (g *Grid) SetReady() {
 
 	allSprites.Sprites = append(allSprites.Sprites, g.FlagsRemaining)
 	allSprites.Sprites = append(allSprites.Sprites, g.TimerElapsed)
-	allSprites.Sprites = append(allSprites.Sprites, g.Super)
+	allSprites.Sprites = append(allSprites.Sprites, g.Summary)
 	allSprites.Sprites = append(allSprites.Sprites, g.Kaboom)
 	allSprites.Sprites = append(allSprites.Sprites, g.Background)
+	allSprites.Sprites = append(allSprites.Sprites, g.AnalysisText)
+	allSprites.Sprites = append(allSprites.Sprites, g.Hover)
+	allSprites.Sprites = append(allSprites.Sprites, g.Face)
+	allSprites.Sprites = append(allSprites.Sprites, g.HUD)
+	allSprites.Sprites = append(allSprites.Sprites, g.HintBox)
+	allSprites.Sprites = append(allSprites.Sprites, g.HintBox.Box)
+	allSprites.Sprites = append(allSprites.Sprites, g.Overlay)
+	allSprites.Sprites = append(allSprites.Sprites, g.Marker)
 	g.State = GAME_READY
 }
 
 func (g *Grid) CheckGameOver() bool {
-	for _, t := range g.Tiles {
-		if t.Covered && !t.HaveFlag {
-			return false
+	// puzzles can also be finished by flagging every mine
+	if g.Puzzle == "" || !g.minesFlagged() {
+		for _, t := range g.Tiles {
+			if t.Covered && !t.HaveFlag {
+				return false
+			}
 		}
 	}
 	g.State = GAME_OVER
+	g.Finish(true)
 	allSprites.TriggerEvent("GameWon")
 	allSprites.TriggerEvent("GameOver")
 	return true
@@ -424,6 +558,9 @@ func (g *Grid) SetSize(w, h int) {
 	g.Width = w
 	g.Height = h
 
+	for _, t := range g.Tiles {
+		allSprites.Remove(t)
+	}
 	g.Tiles = make([]*Tile, 0, 0)
 
 	// Add the tiles
@@ -438,6 +575,9 @@ func (g *Grid) SetSize(w, h int) {
 			allSprites.Sprites = append(allSprites.Sprites, t)
 		}
 	}
+	allSprites.MoveToTop(g.Overlay)
+	allSprites.MoveToTop(g.Hover)
+	allSprites.MoveToTop(g.HintBox.Box)
 }
 
 func (g *Grid) FindTileClicked(x, y int) *Tile {
@@ -456,19 +596,26 @@ func (g *Grid) PlaceBombs(firstTile *Tile) {
 		return
 	}
 
-	g.FlagsRemaining.Remaining = g.TotalBombs
-	allSprites.TriggerEvent("ShowFlagsRemaining")
+	safe := -1
+	if g.Rules.SafeFirstClick {
+		safe = g.GetTilePos(firstTile)
+	}
+	g.LayMines(placeMines(g.Seed, len(g.Tiles), g.TotalBombs, safe))
+}
 
-	var cnt int
+// LayMines puts the bombs at exact positions instead of placing them
+// randomly, e.g. when playing back a replay.
+func (g *Grid) LayMines(mines []int) {
+	if g.State != GAME_STARTED {
+		return
+	}
 
-	for cnt < g.TotalBombs {
-		n := rand.Intn(len(g.Tiles))
-		if g.Tiles[n].HaveBomb || g.Tiles[n] == firstTile {
-			continue
-		}
+	g.TotalBombs = len(mines)
+	g.FlagsRemaining.Remaining = g.TotalBombs
+	allSprites.TriggerEvent("ShowFlagsRemaining")
 
+	for _, n := range mines {
 		g.Tiles[n].HaveBomb = true
-		cnt += 1
 	}
 
 	for cnt, _ := range g.Tiles {
@@ -476,9 +623,96 @@ func (g *Grid) PlaceBombs(firstTile *Tile) {
 	}
 
 	g.State = GAME_RUNNING
+	g.TimerElapsed.Watch.Start()
 	allSprites.TriggerEvent("StartTimer")
 }
 
+// RevealMines shows where every mine was after a loss, and crosses out any
+// flags which were wrong. When staggered, the tiles turn over one ring at a
+// time moving out from the mine which went off.
+func (g *Grid) RevealMines(exploded int, stagger bool) {
+	er, ec := exploded/g.Width, exploded%g.Width
+
+	for cnt, t := range g.Tiles {
+		var tt TileType
+		if t.HaveBomb && !t.HaveFlag && !t.Exploded {
+			tt = TILE_MINE
+		} else if t.HaveFlag && !t.HaveBomb {
+			tt = TILE_MINE_WRONG
+		} else {
+			continue
+		}
+
+		if !stagger || exploded < 0 {
+			t.SetTile(tt)
+			continue
+		}
+
+		dr := cnt/g.Width - er
+		if dr < 0 {
+			dr = -dr
+		}
+		dc := cnt%g.Width - ec
+		if dc < 0 {
+			dc = -dc
+		}
+		dist := dr
+		if dc > dist {
+			dist = dc
+		}
+		t.RevealIn = dist + 1
+		t.RevealAs = tt
+	}
+}
+
+// Reset clears the board so another game can be played on it.
+func (g *Grid) Reset() {
+	g.State = GAME_READY
+	g.Paused = false
+	g.Moves = nil
+	g.Result = nil
+	g.ReplayFile = ""
+	g.Analysis = false
+	g.Assisted = false
+	g.Scored = false
+	g.Code = false
+	g.Hints = 0
+	g.Guesses = nil
+	g.Layout = nil
+	g.StartCells = nil
+	g.Start = -1
+	g.Daily = ""
+	g.Puzzle = ""
+	g.History = History{}
+	g.clearSparks()
+	g.TimerElapsed.Watch.Reset()
+	allSprites.TriggerEvent("NewGame")
+	g.SetSize(g.Width, g.Height)
+	allSprites.MoveToTop(g.Kaboom)
+	allSprites.MoveToTop(g.Summary)
+}
+
+// Finish stops the clock on the final reveal and records the result. A game
+// which is undone and finished again in analysis mode is only recorded once.
+func (g *Grid) Finish(won bool) {
+	g.TimerElapsed.Watch.Stop()
+	g.Result = &Result{
+		Won:        won,
+		Assisted:   g.Assisted,
+		Hints:      g.Hints,
+		Elapsed:    g.TimerElapsed.Watch.Elapsed(),
+		FinishedAt: gameClock.Now(),
+	}
+	if g.Scored {
+		return
+	}
+	g.Scored = true
+	g.recordScore()
+	g.recordDaily()
+	g.recordPuzzle()
+	g.recordCard()
+}
+
 func (g *Grid) FindSurroundingBombs(pos int) {
 	r := pos / g.Width
 	c := pos % g.Width
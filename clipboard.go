@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// clipboard is what to put on the clipboard once the terminal has been put
+// back. The escape can't be written while the screen is being drawn, since
+// it would land in the middle of the screen updates.
+var clipboard string
+
+// copyToClipboard puts s on the clipboard when bombitron quits. Only the last
+// thing copied is kept.
+func copyToClipboard(s string) {
+	clipboard = s
+}
+
+// flushClipboard asks the terminal to put the copied text on the clipboard
+// with the OSC 52 escape. Not every terminal supports it, and there's no way
+// to tell if it worked. Inside tmux the escape has to be passed through to
+// the outer terminal.
+func flushClipboard() error {
+	if clipboard == "" {
+		return nil
+	}
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(clipboard)))
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	_, err := os.Stdout.WriteString(seq)
+	return err
+}
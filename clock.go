@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to the game. The real clock is used for
+// normal play, but it can be swapped out so that tests and replays get a
+// deterministic time source.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock which only moves when it's told to.
+type ManualClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+var gameClock Clock = realClock{}
+
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{t: t}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// Stopwatch measures the time between the first and the final reveal of a
+// game, leaving out any time spent paused. It reads the time from a Clock
+// instead of calling time.Now() directly.
+type Stopwatch struct {
+	mu      sync.Mutex
+	clock   Clock
+	banked  time.Duration
+	since   time.Time
+	running bool
+}
+
+func NewStopwatch(c Clock) *Stopwatch {
+	return &Stopwatch{clock: c}
+}
+
+func (s *Stopwatch) Start() {
+	s.Resume(0)
+}
+
+// Resume restarts the stopwatch as if it had already been running for d.
+func (s *Stopwatch) Resume(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.banked = d
+	s.since = s.clock.Now()
+	s.running = true
+}
+
+func (s *Stopwatch) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return
+	}
+	s.banked += s.clock.Now().Sub(s.since)
+	s.running = false
+}
+
+// Continue starts a stopped stopwatch again without losing the time which
+// has already been measured.
+func (s *Stopwatch) Continue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.since = s.clock.Now()
+	s.running = true
+}
+
+func (s *Stopwatch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.banked = 0
+	s.since = time.Time{}
+	s.running = false
+}
+
+func (s *Stopwatch) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *Stopwatch) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return s.banked + s.clock.Now().Sub(s.since)
+	}
+	return s.banked
+}
+
+// formatElapsed shows a duration in seconds with a given number of decimal
+// places. The value is truncated instead of rounded so that the display never
+// runs ahead of the clock.
+func formatElapsed(d time.Duration, precision int) string {
+	if precision < 0 {
+		precision = 0
+	} else if precision > 3 {
+		precision = 3
+	}
+	unit := time.Duration(math.Pow10(9 - precision))
+	d = d.Truncate(unit)
+	return fmt.Sprintf("%.*f", precision, d.Seconds())
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var testEpoch = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		d         time.Duration
+		precision int
+		want      string
+	}{
+		{0, 0, "0"},
+		{1999 * time.Millisecond, 0, "1"},
+		{1999 * time.Millisecond, 1, "1.9"},
+		{1999 * time.Millisecond, 2, "1.99"},
+		{1999 * time.Millisecond, 3, "1.999"},
+		{1999 * time.Millisecond, 5, "1.999"},
+		{1999 * time.Millisecond, -1, "1"},
+		{61*time.Second + 50*time.Millisecond, 2, "61.05"},
+	}
+	for _, tt := range tests {
+		if got := formatElapsed(tt.d, tt.precision); got != tt.want {
+			t.Errorf("formatElapsed(%v, %d) = %q, want %q", tt.d, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	c := NewManualClock(testEpoch)
+	if got := c.Now(); !got.Equal(testEpoch) {
+		t.Fatalf("Now() = %v, want %v", got, testEpoch)
+	}
+	c.Advance(3 * time.Second)
+	if got := c.Now().Sub(testEpoch); got != 3*time.Second {
+		t.Errorf("after Advance, elapsed = %v, want 3s", got)
+	}
+	c.Set(testEpoch)
+	if got := c.Now(); !got.Equal(testEpoch) {
+		t.Errorf("after Set, Now() = %v, want %v", got, testEpoch)
+	}
+}
+
+func TestStopwatch(t *testing.T) {
+	// each step is applied in order and then the elapsed time is checked
+	type step struct {
+		do      func(s *Stopwatch, c *ManualClock)
+		elapsed time.Duration
+		running bool
+	}
+	advance := func(d time.Duration) func(*Stopwatch, *ManualClock) {
+		return func(_ *Stopwatch, c *ManualClock) { c.Advance(d) }
+	}
+	start := func(s *Stopwatch, _ *ManualClock) { s.Start() }
+	stop := func(s *Stopwatch, _ *ManualClock) { s.Stop() }
+	cont := func(s *Stopwatch, _ *ManualClock) { s.Continue() }
+	reset := func(s *Stopwatch, _ *ManualClock) { s.Reset() }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"not started", []step{
+			{advance(time.Second), 0, false},
+		}},
+		{"running", []step{
+			{start, 0, true},
+			{advance(1500 * time.Millisecond), 1500 * time.Millisecond, true},
+		}},
+		{"paused time is left out", []step{
+			{start, 0, true},
+			{advance(time.Second), time.Second, true},
+			{stop, time.Second, false},
+			{advance(time.Minute), time.Second, false},
+			{cont, time.Second, true},
+			{advance(2 * time.Second), 3 * time.Second, true},
+		}},
+		{"stopping twice", []step{
+			{start, 0, true},
+			{advance(time.Second), time.Second, true},
+			{stop, time.Second, false},
+			{stop, time.Second, false},
+		}},
+		{"continuing while running", []step{
+			{start, 0, true},
+			{advance(time.Second), time.Second, true},
+			{cont, time.Second, true},
+			{advance(time.Second), 2 * time.Second, true},
+		}},
+		{"resume", []step{
+			{func(s *Stopwatch, _ *ManualClock) { s.Resume(5 * time.Second) }, 5 * time.Second, true},
+			{advance(time.Second), 6 * time.Second, true},
+		}},
+		{"reset", []step{
+			{start, 0, true},
+			{advance(time.Second), time.Second, true},
+			{reset, 0, false},
+			{advance(time.Second), 0, false},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewManualClock(testEpoch)
+			s := NewStopwatch(c)
+			for cnt, st := range tt.steps {
+				st.do(s, c)
+				if got := s.Elapsed(); got != st.elapsed {
+					t.Errorf("step %d: Elapsed() = %v, want %v", cnt, got, st.elapsed)
+				}
+				if got := s.Running(); got != st.running {
+					t.Errorf("step %d: Running() = %v, want %v", cnt, got, st.running)
+				}
+			}
+		})
+	}
+}
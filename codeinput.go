@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+
+	sprite "github.com/pdevine/go-asciisprite"
+	tm "github.com/pdevine/go-asciisprite/termbox"
+)
+
+const maxCodeLength = 64
+
+// CodeInput is the box on the title screen for typing or pasting in a game
+// code.
+type CodeInput struct {
+	sprite.BaseSprite
+	font    *sprite.Font
+	Text    string
+	Message string
+}
+
+func NewCodeInput() *CodeInput {
+	c := &CodeInput{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	c.Init()
+
+	c.RegisterEvent("resizeScreen", func() {
+		if c.Visible {
+			c.draw()
+		}
+	})
+
+	return c
+}
+
+// Open shows the box with some text already filled in, and a message to go
+// with it.
+func (c *CodeInput) Open(text, msg string) {
+	c.Text = text
+	c.Message = msg
+	c.draw()
+	c.Visible = true
+	allSprites.MoveToTop(c)
+}
+
+func (c *CodeInput) Close() {
+	c.Visible = false
+}
+
+// HandleKey types into the box, and returns the code once Enter is pressed
+// on one which can be played.
+func (c *CodeInput) HandleKey(ev tm.Event) *GameCode {
+	switch {
+	case ev.Key == tm.KeyEsc:
+		c.Close()
+		return nil
+	case ev.Key == tm.KeyBackspace || ev.Key == tm.KeyBackspace2:
+		if len(c.Text) > 0 {
+			c.Text = c.Text[:len(c.Text)-1]
+		}
+		c.Message = ""
+	case ev.Key == tm.KeyEnter:
+		gc, err := ParseGameCode(c.Text)
+		if err != nil {
+			c.Message = err.Error()
+		} else if !gc.Fits() {
+			c.Message = "board too big for this terminal"
+		} else {
+			c.Close()
+			return gc
+		}
+	case ev.Ch != 0 && len(c.Text) < maxCodeLength:
+		if ch := strings.ToUpper(string(ev.Ch)); strings.ContainsAny(ch, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567") {
+			c.Text += ch
+			c.Message = ""
+		}
+	}
+	c.draw()
+	return nil
+}
+
+func (c *CodeInput) draw() {
+	w := Width - 2*SUMMARY_PAD - 16
+	if w > 140 {
+		w = 140
+	}
+
+	// only the end of a long code fits in the box
+	text := c.Text + "-"
+	if n := (w - 4) / 4; len(text) > n {
+		text = text[len(text)-n:]
+	}
+
+	lines := 3
+	if c.Message != "" {
+		lines++
+	}
+	surf := panelSurface(w, lines*LINE_HEIGHT+4)
+
+	t := textSurface(c.font, "game code", 'b')
+	surf.Blit(t, surf.Width/2-t.Width/2, SUMMARY_PAD)
+	y := SUMMARY_PAD + LINE_HEIGHT + 1
+	surf.Rectangle(SUMMARY_PAD, y, SUMMARY_PAD+w-1, y+LINE_HEIGHT+1, 'G')
+	surf.Blit(textSurface(c.font, text, 'X'), SUMMARY_PAD+2, y+1)
+	y += LINE_HEIGHT + 3
+
+	hint := "enter to play, esc to close"
+	if len(hint)*4 > w {
+		hint = "enter to play"
+	}
+	surf.Blit(textSurface(c.font, hint, 'G'), SUMMARY_PAD, y)
+	if c.Message != "" {
+		surf.Blit(textSurface(c.font, c.Message, 'r'), SUMMARY_PAD, y+LINE_HEIGHT)
+	}
+
+	c.BlockCostumes = []*sprite.Surface{&surf}
+	c.SetCostume(0)
+	c.X = Width/2 - surf.Width/2
+	c.Y = Height/2 - surf.Height/2
+	if c.Y < 0 {
+		c.Y = 0
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const crashRecentMoves = 25
+
+// crashes carries panics from the game's helper goroutines back to main so
+// they go through the same recovery as a panic in the main loop.
+var crashes = make(chan interface{}, 1)
+
+// goSafe runs fn in a goroutine, handing any panic over to main. Only the
+// first crash is reported, since it takes the game down; any which come in
+// behind it are dropped rather than left waiting forever.
+func goSafe(fn func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				select {
+				case crashes <- fmt.Sprintf("%v\n\n%s", r, debug.Stack()):
+				default:
+				}
+			}
+		}()
+		fn()
+	}()
+}
+
+func crashReport(r interface{}, stack []byte) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "bombitron crashed at %s\n\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "panic: %v\n\n", r)
+
+	if g := gameGrid; g != nil {
+		fmt.Fprintf(&b, "state:      %d\n", g.State)
+		fmt.Fprintf(&b, "difficulty: %s\n", g.Difficulty)
+		fmt.Fprintf(&b, "board:      %dx%d, %d bombs\n", g.Width, g.Height, g.TotalBombs)
+		fmt.Fprintf(&b, "seed:       %d\n", g.Seed)
+		fmt.Fprintf(&b, "rules:      %+v\n\n", g.Rules)
+
+		w := g.Width
+		if w == 0 {
+			w = 1
+		}
+		fmt.Fprintf(&b, "recent moves (of %d):\n", len(g.Moves))
+		for _, m := range g.RecentMoves(crashRecentMoves) {
+			fmt.Fprintf(&b, "  %10s  %-8s %d,%d\n", m.T.Truncate(time.Millisecond), m.Kind, m.Pos%w, m.Pos/w)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("stack:\n")
+	b.Write(stack)
+	return b.String()
+}
+
+// writeCrashReport saves a crash report in the data directory, falling back to
+// the temp directory if that isn't writable.
+func writeCrashReport(r interface{}, stack []byte) (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		d = os.TempDir()
+	}
+	fn := filepath.Join(d, fmt.Sprintf("crash-%s.txt", time.Now().UTC().Format("20060102-150405")))
+	return fn, ioutil.WriteFile(fn, []byte(crashReport(r, stack)), 0644)
+}
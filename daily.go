@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+// The daily board is always the same size, whatever the terminal, so that
+// everyone gets the same layout.
+const (
+	DAILY_WIDTH  = 16
+	DAILY_HEIGHT = 8
+)
+
+// A DailyRecord is how you got on with one day's board on one difficulty.
+// The time is from the first attempt which cleared it.
+type DailyRecord struct {
+	Date       string `json:"date"`
+	Difficulty string `json:"difficulty"`
+	Seed       int64  `json:"seed"`
+	Attempts   int    `json:"attempts"`
+	Completed  bool   `json:"completed"`
+	ElapsedMs  int64  `json:"elapsed_ms,omitempty"`
+}
+
+func dailyDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// dailySeed turns the date and difficulty into the seed for the board.
+func dailySeed(date, difficulty string) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "bombitron daily %s %s", date, difficulty)
+	return int64(h.Sum64() &^ (1 << 63))
+}
+
+// dailyFits checks that the terminal is big enough for the daily board.
+func dailyFits() bool {
+	return Width/TILE_WIDTH >= DAILY_WIDTH && (Height-HEADER_OFFSET)/TILE_HEIGHT >= DAILY_HEIGHT
+}
+
+// dailyLayout picks the start tile and then lays the mines anywhere except
+// on or around it, so the start always opens up.
+func dailyLayout(seed int64, mines int) (int, []int) {
+	r := rand.New(rand.NewSource(seed))
+	n := DAILY_WIDTH * DAILY_HEIGHT
+	start := r.Intn(n)
+
+	taken := make([]bool, n)
+	sr, sc := start/DAILY_WIDTH, start%DAILY_WIDTH
+	for pos := range taken {
+		dr, dc := pos/DAILY_WIDTH-sr, pos%DAILY_WIDTH-sc
+		if dr >= -1 && dr <= 1 && dc >= -1 && dc <= 1 {
+			taken[pos] = true
+		}
+	}
+
+	layout := make([]int, 0, mines)
+	for len(layout) < mines {
+		pos := r.Intn(n)
+		if taken[pos] {
+			continue
+		}
+		taken[pos] = true
+		layout = append(layout, pos)
+	}
+	return start, layout
+}
+
+// StartDaily sets up today's board for a difficulty. The mines are laid on
+// the first reveal like any other game.
+func (g *Grid) StartDaily(difficulty string, rate float64) {
+	date := dailyDate(gameClock.Now())
+	mines := int(math.Round(DAILY_WIDTH * DAILY_HEIGHT * rate))
+
+	g.Reset()
+	g.SetSize(DAILY_WIDTH, DAILY_HEIGHT)
+	g.Difficulty = difficulty
+	g.Daily = date
+	g.Seed = dailySeed(date, difficulty)
+	g.TotalBombs = mines
+	g.Start, g.Layout = dailyLayout(g.Seed, mines)
+	g.Record = settings.RecordReplays
+	g.State = GAME_STARTED
+	allSprites.MoveToTop(g.Marker)
+}
+
+// dailyRate is the bomb rate for a difficulty, for starting another go at
+// the daily board.
+func dailyRate(difficulty string) float64 {
+	switch difficulty {
+	case "med.":
+		return MEDIUM_BOMB_RATE
+	case "hard":
+		return HARD_BOMB_RATE
+	}
+	return EASY_BOMB_RATE
+}
+
+func dailyPath() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "daily.json"), nil
+}
+
+func readDailyRecords(fn string) (map[string]*DailyRecord, error) {
+	recs := map[string]*DailyRecord{}
+	data, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return recs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &recs); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return recs, nil
+}
+
+func loadDailyRecords() (map[string]*DailyRecord, error) {
+	fn, err := dailyPath()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return readDailyRecords(fn)
+}
+
+// recordDaily counts an attempt at the daily board, and keeps the time of
+// the first one which cleared it without any help.
+func (g *Grid) recordDaily() {
+	r := g.Result
+	if r == nil || g.Daily == "" || replayViewer != nil {
+		return
+	}
+
+	game := DailyRecord{Date: g.Daily, Difficulty: g.Difficulty, Seed: g.Seed}
+	if r.Won && !r.Assisted {
+		game.Completed = true
+		game.ElapsedMs = r.Elapsed.Milliseconds()
+	}
+	r.saveRecord(func() recordSave {
+		return recordSave{What: "daily", Err: saveDaily(game)}
+	})
+}
+
+// saveDaily adds an attempt to the daily records. The attempt's time is only
+// kept if it's the first one to clear the board.
+func saveDaily(game DailyRecord) error {
+	fn, err := dailyPath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	recs, err := readDailyRecords(fn)
+	if err != nil {
+		return err
+	}
+	key := game.Date + " " + game.Difficulty
+	rec, ok := recs[key]
+	if !ok {
+		rec = &DailyRecord{Date: game.Date, Difficulty: game.Difficulty, Seed: game.Seed}
+		recs[key] = rec
+	}
+	rec.Attempts++
+	if game.Completed && !rec.Completed {
+		rec.Completed = true
+		rec.ElapsedMs = game.ElapsedMs
+	}
+
+	data, err := json.MarshalIndent(recs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(fn, data); err != nil {
+		return err
+	}
+	setExitMessage(key, rec.ShareCode())
+	return nil
+}
+
+// ShareCode sums up a daily result in a line for pasting into chat. The
+// check digits on the end are a checksum of the board and the result. They
+// only catch a mistyped line; everything that goes into them is public, so
+// they don't show that the board was really played.
+func (rec *DailyRecord) ShareCode() string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%d|%d|%t|%d", rec.Date, rec.Difficulty, rec.Seed, rec.Attempts, rec.Completed, rec.ElapsedMs)
+	check := h.Sum32() & 0xffffff
+
+	tries := "1 try"
+	if rec.Attempts != 1 {
+		tries = fmt.Sprintf("%d tries", rec.Attempts)
+	}
+	if !rec.Completed {
+		return fmt.Sprintf("bombitron daily %s %s: not cleared, %s #%06x", rec.Date, rec.Difficulty, tries, check)
+	}
+	return fmt.Sprintf("bombitron daily %s %s: cleared in %ss, %s #%06x", rec.Date, rec.Difficulty,
+		formatElapsed(time.Duration(rec.ElapsedMs)*time.Millisecond, 2), tries, check)
+}
+
+// StartMarker shows which tile to start on, for boards which were laid out
+// ahead of time like the daily board or one from a game code.
+type StartMarker struct {
+	sprite.BaseSprite
+}
+
+func NewStartMarker() *StartMarker {
+	m := &StartMarker{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+	}
+	m.Init()
+
+	surf := sprite.NewSurface(TILE_WIDTH, TILE_HEIGHT, true)
+	surf.Rectangle(0, 0, TILE_WIDTH-1, TILE_HEIGHT-1, 'o')
+	surf.Rectangle(1, 1, TILE_WIDTH-2, TILE_HEIGHT-2, 'o')
+	m.BlockCostumes = []*sprite.Surface{&surf}
+	return m
+}
+
+func (m *StartMarker) Update() {
+	g := gameGrid
+	if g.Layout == nil || g.State != GAME_STARTED || g.Start < 0 || g.Start >= len(g.Tiles) {
+		m.Visible = false
+		return
+	}
+	t := g.Tiles[g.Start]
+	m.X = t.GridX
+	m.Y = t.GridY
+	m.Visible = true
+}
+
+// runDaily prints the share codes for today's boards.
+func runDaily(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: bombitron daily")
+		return 1
+	}
+	recs, err := loadDailyRecords()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	date := dailyDate(gameClock.Now())
+	played := false
+	for _, d := range difficulties {
+		if rec, ok := recs[date+" "+d]; ok {
+			fmt.Println(rec.ShareCode())
+			played = true
+		}
+	}
+	if !played {
+		fmt.Printf("you haven't played the daily board for %s yet\n", date)
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDailyDate(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{testEpoch, "2026-01-02"},
+		{time.Date(2026, 1, 2, 23, 30, 0, 0, time.FixedZone("west", -3*3600)), "2026-01-03"},
+		{time.Date(2026, 1, 2, 1, 0, 0, 0, time.FixedZone("east", 9*3600)), "2026-01-01"},
+	}
+	for _, tt := range tests {
+		c := NewManualClock(tt.t)
+		if got := dailyDate(c.Now()); got != tt.want {
+			t.Errorf("dailyDate(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestDailySeed(t *testing.T) {
+	tests := []struct {
+		date, difficulty string
+	}{
+		{"2026-01-02", "easy"},
+		{"2026-01-02", "med."},
+		{"2026-01-02", "hard"},
+		{"2026-01-03", "easy"},
+	}
+	seen := map[int64]bool{}
+	for _, tt := range tests {
+		s := dailySeed(tt.date, tt.difficulty)
+		if s != dailySeed(tt.date, tt.difficulty) {
+			t.Errorf("dailySeed(%q, %q) isn't the same twice", tt.date, tt.difficulty)
+		}
+		if s < 0 {
+			t.Errorf("dailySeed(%q, %q) = %d, want a positive seed", tt.date, tt.difficulty, s)
+		}
+		if seen[s] {
+			t.Errorf("dailySeed(%q, %q) = %d, which another board has", tt.date, tt.difficulty, s)
+		}
+		seen[s] = true
+	}
+}
+
+func TestDailyLayout(t *testing.T) {
+	for _, mines := range []int{0, 13, 40} {
+		seed := dailySeed("2026-01-02", "easy")
+		start, layout := dailyLayout(seed, mines)
+		start2, layout2 := dailyLayout(seed, mines)
+		if start != start2 || len(layout) != len(layout2) {
+			t.Fatalf("dailyLayout isn't the same twice")
+		}
+		if len(layout) != mines {
+			t.Errorf("laid %d mines, want %d", len(layout), mines)
+		}
+
+		taken := map[int]bool{}
+		sr, sc := start/DAILY_WIDTH, start%DAILY_WIDTH
+		for cnt, m := range layout {
+			if m != layout2[cnt] {
+				t.Errorf("mine %d is at %d and %d", cnt, m, layout2[cnt])
+			}
+			if taken[m] {
+				t.Errorf("two mines at %d", m)
+			}
+			taken[m] = true
+			if dr, dc := m/DAILY_WIDTH-sr, m%DAILY_WIDTH-sc; dr >= -1 && dr <= 1 && dc >= -1 && dc <= 1 {
+				t.Errorf("mine at %d is next to the start at %d", m, start)
+			}
+		}
+	}
+}
+
+func TestShareCode(t *testing.T) {
+	rec := DailyRecord{
+		Date:       "2026-01-02",
+		Difficulty: "easy",
+		Seed:       dailySeed("2026-01-02", "easy"),
+		Attempts:   2,
+		Completed:  true,
+		ElapsedMs:  42170,
+	}
+
+	tests := []struct {
+		name string
+		edit func(r *DailyRecord)
+		want string
+	}{
+		{"cleared", func(r *DailyRecord) {}, "bombitron daily 2026-01-02 easy: cleared in 42.17s, 2 tries #"},
+		{"one try", func(r *DailyRecord) { r.Attempts = 1 }, "cleared in 42.17s, 1 try #"},
+		{"not cleared", func(r *DailyRecord) { r.Completed = false; r.ElapsedMs = 0 }, "easy: not cleared, 2 tries #"},
+	}
+
+	base := rec.ShareCode()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rec
+			tt.edit(&r)
+			got := r.ShareCode()
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("ShareCode() = %q, want it to contain %q", got, tt.want)
+			}
+			check := got[strings.LastIndex(got, "#")+1:]
+			if len(check) != 6 {
+				t.Errorf("check digits %q aren't 6 long", check)
+			}
+			if got != base && check == base[len(base)-6:] {
+				t.Errorf("check digits didn't change with the result")
+			}
+		})
+	}
+}
+
+func TestSaveDaily(t *testing.T) {
+	d := tempDir(t)
+	defer os.RemoveAll(d)
+	old := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", d)
+	defer os.Setenv("XDG_DATA_HOME", old)
+
+	game := DailyRecord{Date: "2026-01-02", Difficulty: "easy", Seed: 42}
+	lost := game
+	won := game
+	won.Completed = true
+	won.ElapsedMs = 42170
+	slower := won
+	slower.ElapsedMs = 50000
+	for _, g := range []DailyRecord{lost, won, slower} {
+		if err := saveDaily(g); err != nil {
+			t.Fatalf("saveDaily() = %v", err)
+		}
+	}
+
+	recs, err := loadDailyRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := recs["2026-01-02 easy"]
+	if rec == nil || rec.Attempts != 3 || !rec.Completed || rec.ElapsedMs != 42170 {
+		t.Errorf("record = %+v, want 3 attempts cleared in 42170ms", rec)
+	}
+}
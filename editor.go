@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+
+	sprite "github.com/pdevine/go-asciisprite"
+	tm "github.com/pdevine/go-asciisprite/termbox"
+)
+
+// Editor lays out a board by hand. Every tile is shown face up with its
+// number, which changes as mines are put down and taken away. The grid stays
+// in GAME_READY the whole time so none of the game gets in the way.
+type Editor struct {
+	File     string
+	Board    *Board
+	Cursor   *EditorCursor
+	Bar      *EditorBar
+	Message  string
+	Painting bool
+	Paint    bool
+}
+
+type EditorBar struct {
+	sprite.BaseSprite
+	font   *sprite.Font
+	editor *Editor
+	drawn  string
+}
+
+type EditorCursor struct {
+	sprite.BaseSprite
+	Pos int
+}
+
+var boardEditor *Editor
+
+// NewEditor edits the board in a file, or starts from an empty one if it
+// doesn't exist yet.
+func NewEditor(fn string) (*Editor, error) {
+	e := &Editor{File: fn}
+	if fn == "" {
+		return e, nil
+	}
+	if _, err := os.Stat(fn); os.IsNotExist(err) {
+		return e, nil
+	}
+	b, err := LoadBoard(fn)
+	if err != nil {
+		return nil, err
+	}
+	e.Board = b
+	return e, nil
+}
+
+// Start puts the board on the screen once the grid is ready. An empty board
+// fills the terminal.
+func (e *Editor) Start() error {
+	if b := e.Board; b != nil && !b.Fits() {
+		return fmt.Errorf("the terminal is too small for a %dx%d board", b.Width, b.Height)
+	}
+
+	e.load()
+
+	// the cursor goes on after the tiles so it's drawn over them
+	e.Cursor = NewEditorCursor()
+	e.Bar = NewEditorBar(e)
+	allSprites.Sprites = append(allSprites.Sprites, e.Cursor)
+	allSprites.Sprites = append(allSprites.Sprites, e.Bar)
+	return nil
+}
+
+// load lays the editor's board out on the grid, face up.
+func (e *Editor) load() {
+	g := gameGrid
+	g.Reset()
+	if b := e.Board; b != nil {
+		g.SetSize(b.Width, b.Height)
+		for _, m := range b.Mines {
+			g.Tiles[m].HaveBomb = true
+		}
+	}
+	g.Face.Visible = false
+	for cnt := range g.Tiles {
+		e.update(cnt)
+	}
+	e.countMines()
+}
+
+// update works the number out again for a tile and turns it face up.
+func (e *Editor) update(pos int) {
+	t := gameGrid.Tiles[pos]
+	t.Covered = false
+	t.BombCount = 0
+	gameGrid.FindSurroundingBombs(pos)
+	if t.HaveBomb {
+		t.SetTile(TILE_MINE)
+	} else {
+		t.SetTile(TileType(t.BombCount))
+	}
+}
+
+// set puts down or takes away a mine, and fixes the numbers around it.
+func (e *Editor) set(pos int, mine bool) {
+	g := gameGrid
+	if g.Tiles[pos].HaveBomb == mine {
+		return
+	}
+	g.Tiles[pos].HaveBomb = mine
+	e.update(pos)
+	for _, n := range g.Neighbours(pos) {
+		e.update(n)
+	}
+	e.countMines()
+}
+
+// countMines shows the number of mines where the flag count usually goes.
+func (e *Editor) countMines() {
+	g := gameGrid
+	g.FlagsRemaining.Remaining = 0
+	for _, t := range g.Tiles {
+		if t.HaveBomb {
+			g.FlagsRemaining.Remaining++
+		}
+	}
+	allSprites.TriggerEvent("ShowFlagsRemaining")
+}
+
+// setAll changes every tile at once, before redrawing them.
+func (e *Editor) setAll(mine func(pos int) bool) {
+	g := gameGrid
+	mines := make([]bool, len(g.Tiles))
+	for cnt := range g.Tiles {
+		mines[cnt] = mine(cnt)
+	}
+	for cnt, t := range g.Tiles {
+		t.HaveBomb = mines[cnt]
+	}
+	for cnt := range g.Tiles {
+		e.update(cnt)
+	}
+	e.countMines()
+}
+
+// mirror copies the left half of the board onto the right, or the top half
+// onto the bottom.
+func (e *Editor) mirror(vertical bool) {
+	g := gameGrid
+	e.setAll(func(pos int) bool {
+		r, c := pos/g.Width, pos%g.Width
+		if vertical && r >= (g.Height+1)/2 {
+			r = g.Height - 1 - r
+		} else if !vertical && c >= (g.Width+1)/2 {
+			c = g.Width - 1 - c
+		}
+		return g.Tiles[r*g.Width+c].HaveBomb
+	})
+}
+
+// fill adds mines at random until the board is as dense as a medium game.
+func (e *Editor) fill() {
+	g := gameGrid
+	free := []int{}
+	total := 0
+	for cnt, t := range g.Tiles {
+		if t.HaveBomb {
+			total++
+		} else {
+			free = append(free, cnt)
+		}
+	}
+	want := int(float64(len(g.Tiles))*MEDIUM_BOMB_RATE) - total
+	rand.Shuffle(len(free), func(i, j int) { free[i], free[j] = free[j], free[i] })
+	for cnt := 0; cnt < want && cnt < len(free); cnt++ {
+		g.Tiles[free[cnt]].HaveBomb = true
+	}
+	e.setAll(func(pos int) bool { return g.Tiles[pos].HaveBomb })
+}
+
+// board is what's been laid out so far.
+func (e *Editor) board() (*Board, error) {
+	g := gameGrid
+	b := &Board{Width: g.Width, Height: g.Height}
+	for cnt, t := range g.Tiles {
+		if t.HaveBomb {
+			b.Mines = append(b.Mines, cnt)
+		}
+	}
+	return b, b.check()
+}
+
+// save writes the board back to the file it came from, or into the boards
+// directory if it didn't come from one.
+func (e *Editor) save() {
+	b, err := e.board()
+	if err != nil {
+		e.Message = err.Error()
+		return
+	}
+	fn := e.File
+	if fn == "" {
+		if fn, err = boardsPath(); err != nil {
+			e.Message = "not saved"
+			return
+		}
+	}
+	if err := WriteBoard(fn, b); err != nil {
+		e.Message = "not saved"
+		setExitMessage("board", fmt.Sprintf("couldn't save the board: %v", err))
+		return
+	}
+	e.File = fn
+	e.Message = "saved"
+	setExitMessage("board", "board saved to "+fn)
+}
+
+// play leaves the editor and starts a game on the board. If the game can't
+// be started the board goes back into the editor.
+func (e *Editor) play() {
+	b, err := e.board()
+	if err != nil {
+		e.Message = err.Error()
+		return
+	}
+	if err := gameGrid.StartBoard(b); err != nil {
+		e.Board = b
+		e.load()
+		allSprites.MoveToTop(e.Cursor)
+		allSprites.MoveToTop(e.Bar)
+		e.Message = "can't play this board, " + err.Error()
+		return
+	}
+	e.Cursor.Visible = false
+	e.Bar.Visible = false
+	allSprites.Remove(e.Cursor)
+	allSprites.Remove(e.Bar)
+	boardEditor = nil
+	gameGrid.Face.Visible = true
+}
+
+func (e *Editor) HandleKey(ev tm.Event) {
+	c := e.Cursor
+	e.Message = ""
+	switch {
+	case ev.Key == tm.KeyArrowUp:
+		c.MoveBy(0, -1)
+	case ev.Key == tm.KeyArrowDown:
+		c.MoveBy(0, 1)
+	case ev.Key == tm.KeyArrowLeft:
+		c.MoveBy(-1, 0)
+	case ev.Key == tm.KeyArrowRight:
+		c.MoveBy(1, 0)
+	case (ev.Key == tm.KeyEnter || ev.Key == tm.KeySpace) && c.Pos >= 0:
+		e.set(c.Pos, !gameGrid.Tiles[c.Pos].HaveBomb)
+	case ev.Ch == 'm':
+		e.mirror(false)
+	case ev.Ch == 'M':
+		e.mirror(true)
+	case ev.Ch == 'f':
+		e.fill()
+	case ev.Ch == 'c':
+		e.setAll(func(int) bool { return false })
+	case ev.Ch == 's':
+		e.save()
+	case ev.Ch == 'p':
+		e.play()
+	}
+}
+
+// HandleMouse puts down or takes away mines. Dragging carries on doing
+// whichever the first tile needed.
+func (e *Editor) HandleMouse(ev tm.Event) {
+	t := gameGrid.FindTileClicked(MouseX, MouseY)
+	if t != nil {
+		e.Cursor.Pos = gameGrid.GetTilePos(t)
+	}
+
+	switch ev.Key {
+	case tm.MouseLeft:
+		if t == nil {
+			return
+		}
+		if !e.Painting {
+			e.Painting = true
+			e.Paint = !t.HaveBomb
+			e.Message = ""
+		}
+		e.set(e.Cursor.Pos, e.Paint)
+	case tm.MouseRelease:
+		e.Painting = false
+	}
+}
+
+func NewEditorBar(e *Editor) *EditorBar {
+	b := &EditorBar{BaseSprite: sprite.BaseSprite{
+		X:       24,
+		Y:       1,
+		Visible: true},
+		font:   sprite.NewPakuFont(),
+		editor: e,
+	}
+	b.Init()
+	return b
+}
+
+// Update shows the size and density of the board next to the mine count,
+// along with anything the last tool had to say.
+func (b *EditorBar) Update() {
+	g := gameGrid
+	mines := g.FlagsRemaining.Remaining
+	s := fmt.Sprintf("edit %dx%d %d%%", g.Width, g.Height, mines*100/len(g.Tiles))
+	if b.editor.Message != "" {
+		s += " - " + b.editor.Message
+	}
+	if s == b.drawn {
+		return
+	}
+	b.drawn = s
+
+	surf := textSurface(b.font, s, 'X')
+	b.BlockCostumes = []*sprite.Surface{&surf}
+	b.SetCostume(0)
+}
+
+// NewEditorCursor draws a box around the tile the keyboard is on.
+func NewEditorCursor() *EditorCursor {
+	c := &EditorCursor{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		Pos: -1,
+	}
+	c.Init()
+
+	surf := sprite.NewSurface(TILE_WIDTH, TILE_HEIGHT, true)
+	surf.Rectangle(0, 0, TILE_WIDTH-1, TILE_HEIGHT-1, 'y')
+	c.BlockCostumes = []*sprite.Surface{&surf}
+	return c
+}
+
+// MoveBy moves the cursor, starting from the middle of the board.
+func (c *EditorCursor) MoveBy(dc, dr int) {
+	g := gameGrid
+	if c.Pos == -1 {
+		c.Pos = (g.Height/2)*g.Width + g.Width/2
+		return
+	}
+	r := c.Pos/g.Width + dr
+	col := c.Pos%g.Width + dc
+	if r < 0 || col < 0 || r >= g.Height || col >= g.Width {
+		return
+	}
+	c.Pos = r*g.Width + col
+}
+
+func (c *EditorCursor) Update() {
+	g := gameGrid
+	if c.Pos < 0 || c.Pos >= len(g.Tiles) {
+		c.Visible = false
+		return
+	}
+	t := g.Tiles[c.Pos]
+	c.X = t.GridX
+	c.Y = t.GridY
+	c.Visible = true
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+)
+
+const codeVersion = 1
+
+const (
+	CODE_SAFE_FIRST_CLICK = 1 << iota
+	CODE_QUESTION_MARKS
+	CODE_DAILY
+)
+
+// Codes are written in base32 since the font has every letter and digit in
+// it, and case doesn't matter when typing one in.
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var errNoCode = errors.New("this game doesn't have a code")
+
+// A GameCode is everything needed to lay out the same board somewhere else.
+// The mines depend on the tile which was clicked first, so that's included
+// and shown to whoever plays it next.
+type GameCode struct {
+	Width      int
+	Height     int
+	Mines      int
+	Seed       int64
+	Rules      Rules
+	Difficulty string
+	Daily      bool
+	Start      int
+}
+
+// placeMines picks the positions of total mines on a board of n tiles,
+// steering clear of the safe tile if there is one.
+func placeMines(seed int64, n, total, safe int) []int {
+	r := rand.New(rand.NewSource(seed))
+	taken := make([]bool, n)
+	mines := make([]int, 0, total)
+
+	for len(mines) < total {
+		pos := r.Intn(n)
+		if taken[pos] || pos == safe {
+			continue
+		}
+		taken[pos] = true
+		mines = append(mines, pos)
+	}
+	return mines
+}
+
+// NewGameCode makes a code for a board once its mines have been laid.
+func NewGameCode(g *Grid) (*GameCode, error) {
+	if g.Start < 0 || g.Seed == 0 || (g.State != GAME_RUNNING && g.State != GAME_OVER) {
+		return nil, errNoCode
+	}
+	return &GameCode{
+		Width:      g.Width,
+		Height:     g.Height,
+		Mines:      g.TotalBombs,
+		Seed:       g.Seed,
+		Rules:      g.Rules,
+		Difficulty: g.Difficulty,
+		Daily:      g.Daily != "",
+		Start:      g.Start,
+	}, nil
+}
+
+// Layout works out where the mines go.
+func (gc *GameCode) Layout() []int {
+	if gc.Daily {
+		_, mines := dailyLayout(gc.Seed, gc.Mines)
+		return mines
+	}
+	safe := -1
+	if gc.Rules.SafeFirstClick {
+		safe = gc.Start
+	}
+	return placeMines(gc.Seed, gc.Width*gc.Height, gc.Mines, safe)
+}
+
+func (gc *GameCode) String() string {
+	var flags byte
+	if gc.Rules.SafeFirstClick {
+		flags |= CODE_SAFE_FIRST_CLICK
+	}
+	if gc.Rules.QuestionMarks {
+		flags |= CODE_QUESTION_MARKS
+	}
+	if gc.Daily {
+		flags |= CODE_DAILY
+	}
+	diff := byte(255)
+	for cnt, d := range difficulties {
+		if d == gc.Difficulty {
+			diff = byte(cnt)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(codeVersion)
+	b.WriteByte(flags)
+	b.WriteByte(diff)
+	for _, v := range []int{gc.Width, gc.Height, gc.Mines, gc.Start} {
+		buf := make([]byte, binary.MaxVarintLen64)
+		b.Write(buf[:binary.PutUvarint(buf, uint64(v))])
+	}
+	binary.Write(&b, binary.BigEndian, gc.Seed)
+
+	h := fnv.New32a()
+	h.Write(b.Bytes())
+	b.WriteByte(byte(h.Sum32()))
+	return codeEncoding.EncodeToString(b.Bytes())
+}
+
+// ParseGameCode reads a code back. Spaces and dashes are ignored so that
+// long codes can be split up.
+func ParseGameCode(s string) (*GameCode, error) {
+	s = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "\n", "").Replace(s))
+	data, err := codeEncoding.DecodeString(s)
+	if err != nil || len(data) < 4 {
+		return nil, errors.New("not a game code")
+	}
+
+	sum := data[len(data)-1]
+	data = data[:len(data)-1]
+	h := fnv.New32a()
+	h.Write(data)
+	if byte(h.Sum32()) != sum {
+		return nil, errors.New("game code has a typo in it")
+	}
+	if data[0] > codeVersion {
+		return nil, fmt.Errorf("game code is from a newer version of bombitron (version %d)", data[0])
+	} else if data[0] != codeVersion {
+		return nil, errors.New("not a game code")
+	}
+
+	flags := data[1]
+	gc := &GameCode{
+		Rules: Rules{
+			SafeFirstClick: flags&CODE_SAFE_FIRST_CLICK != 0,
+			QuestionMarks:  flags&CODE_QUESTION_MARKS != 0,
+		},
+		Daily:      flags&CODE_DAILY != 0,
+		Difficulty: "custom",
+	}
+	if int(data[2]) < len(difficulties) {
+		gc.Difficulty = difficulties[data[2]]
+	}
+
+	r := bytes.NewReader(data[3:])
+	for _, v := range []*int{&gc.Width, &gc.Height, &gc.Mines, &gc.Start} {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > 1<<20 {
+			return nil, errors.New("game code is damaged")
+		}
+		*v = int(n)
+	}
+	if err := binary.Read(r, binary.BigEndian, &gc.Seed); err != nil || r.Len() != 0 {
+		return nil, errors.New("game code is damaged")
+	}
+
+	n := gc.Width * gc.Height
+	switch {
+	case gc.Width == 0 || gc.Height == 0:
+		return nil, fmt.Errorf("invalid board size %dx%d", gc.Width, gc.Height)
+	case gc.Mines >= n-9:
+		return nil, fmt.Errorf("too many mines (%d) for a %dx%d board", gc.Mines, gc.Width, gc.Height)
+	case gc.Start >= n:
+		return nil, errors.New("game code starts off the board")
+	case gc.Daily && (gc.Width != DAILY_WIDTH || gc.Height != DAILY_HEIGHT):
+		return nil, errors.New("game code is damaged")
+	}
+	return gc, nil
+}
+
+// Fits checks that the board will fit in the terminal.
+func (gc *GameCode) Fits() bool {
+	return Width/TILE_WIDTH >= gc.Width && (Height-HEADER_OFFSET)/TILE_HEIGHT >= gc.Height
+}
+
+// StartCode sets up the board from a code. The tile the code was started
+// from is marked, since the board is only the same when it's opened there.
+func (g *Grid) StartCode(gc *GameCode) {
+	g.Reset()
+	g.SetSize(gc.Width, gc.Height)
+	g.Difficulty = gc.Difficulty
+	g.Seed = gc.Seed
+	g.Rules = gc.Rules
+	g.TotalBombs = gc.Mines
+	g.Start = gc.Start
+	g.Layout = gc.Layout()
+	g.Code = true
+	g.Record = settings.RecordReplays
+	g.State = GAME_STARTED
+	allSprites.MoveToTop(g.Marker)
+}
+
+// playCode and playBoard are given on the command line, and started as soon
+// as the screen is ready.
+var (
+	playCode  string
+	playBoard *Board
+)
+
+// parsePlayArgs checks the code or board passed to the play command up
+// front, so a bad one is reported before the screen gets taken over.
+func parsePlayArgs(args []string) error {
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	code := fs.String("code", "", "game code to play")
+	board := fs.String("board", "", "board file to play")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*code == "") == (*board == "") || fs.NArg() > 0 {
+		return errors.New("usage: bombitron play --code <code> | --board <file>")
+	}
+	if *board != "" {
+		b, err := LoadBoard(*board)
+		if err != nil {
+			return err
+		}
+		playBoard = b
+		return nil
+	}
+	if _, err := ParseGameCode(*code); err != nil {
+		return err
+	}
+	playCode = *code
+	return nil
+}
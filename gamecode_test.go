@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGameCodeRoundTrip(t *testing.T) {
+	tests := []GameCode{
+		{Width: 16, Height: 8, Mines: 13, Seed: 1, Difficulty: "easy", Start: 0},
+		{Width: 30, Height: 16, Mines: 99, Seed: 1 << 62, Difficulty: "hard", Start: 479,
+			Rules: Rules{SafeFirstClick: true, QuestionMarks: true}},
+		{Width: 200, Height: 100, Mines: 4000, Seed: 123456789, Difficulty: "custom", Start: 12345},
+		{Width: DAILY_WIDTH, Height: DAILY_HEIGHT, Mines: 20, Seed: dailySeed("2026-01-02", "med."),
+			Difficulty: "med.", Daily: true, Start: 7},
+	}
+
+	for _, gc := range tests {
+		code := gc.String()
+		got, err := ParseGameCode(code)
+		if err != nil {
+			t.Errorf("ParseGameCode(%q) = %v", code, err)
+			continue
+		}
+		if *got != gc {
+			t.Errorf("ParseGameCode(%q) = %+v, want %+v", code, *got, gc)
+		}
+
+		// case, spaces and dashes don't matter
+		messy := strings.ToLower(code[:5]) + " - " + code[5:]
+		if got, err := ParseGameCode(messy); err != nil || *got != gc {
+			t.Errorf("ParseGameCode(%q) = %+v, %v", messy, got, err)
+		}
+	}
+}
+
+func TestParseGameCode(t *testing.T) {
+	good := (&GameCode{Width: 16, Height: 8, Mines: 13, Seed: 42, Difficulty: "easy", Start: 3}).String()
+	typo := []byte(good)
+	if typo[10] == 'A' {
+		typo[10] = 'B'
+	} else {
+		typo[10] = 'A'
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"empty", "", "not a game code"},
+		{"not base32", "hello!", "not a game code"},
+		{"typo", string(typo), ""},
+		{"too many mines", (&GameCode{Width: 4, Height: 4, Mines: 10, Seed: 1}).String(), "too many mines"},
+		{"no size", (&GameCode{Width: 0, Height: 4, Seed: 1}).String(), "board size"},
+		{"start off the board", (&GameCode{Width: 4, Height: 4, Mines: 1, Seed: 1, Start: 16}).String(), "off the board"},
+		{"daily with the wrong size", (&GameCode{Width: 10, Height: 10, Mines: 1, Seed: 1, Daily: true}).String(), "damaged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGameCode(tt.code)
+			if err == nil {
+				t.Fatalf("ParseGameCode(%q) worked, want an error", tt.code)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseGameCode(%q) = %v, want an error containing %q", tt.code, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameCodeLayout(t *testing.T) {
+	gc := GameCode{Width: 9, Height: 9, Mines: 10, Seed: 7, Start: 40, Rules: Rules{SafeFirstClick: true}}
+	a, b := gc.Layout(), gc.Layout()
+	if len(a) != gc.Mines {
+		t.Fatalf("laid %d mines, want %d", len(a), gc.Mines)
+	}
+	for cnt := range a {
+		if a[cnt] != b[cnt] {
+			t.Errorf("mine %d is at %d and then %d", cnt, a[cnt], b[cnt])
+		}
+		if a[cnt] == gc.Start {
+			t.Errorf("mine laid on the safe start tile")
+		}
+	}
+}
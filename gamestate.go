@@ -1,8 +1,16 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"math"
 	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"syscall"
 	"time"
 
 	sprite "github.com/pdevine/go-asciisprite"
@@ -46,14 +54,108 @@ func setPalette() {
 }
 
 func main() {
+	parseFlags()
+
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "stats":
+			os.Exit(runStats(args[1:]))
+		case "daily":
+			os.Exit(runDaily(args[1:]))
+		case "edit":
+			if len(args) > 2 {
+				fmt.Fprintln(os.Stderr, "usage: bombitron edit [file]")
+				os.Exit(1)
+			}
+			fn := ""
+			if len(args) == 2 {
+				fn = args[1]
+			}
+			e, err := NewEditor(fn)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			boardEditor = e
+		case "convert":
+			os.Exit(runConvert(args[1:]))
+		case "play":
+			if err := parsePlayArgs(args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "replay":
+			if len(args) != 2 {
+				fmt.Fprintln(os.Stderr, "usage: bombitron replay <file>")
+				os.Exit(1)
+			}
+			v, err := loadReplayViewer(args[1])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			replayViewer = v
+			gameClock = v.Clock
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+			os.Exit(1)
+		}
+	}
+
+	code := run()
+	recordWrites.Wait()
+	flushClipboard()
+	for _, k := range exitOrder {
+		fmt.Println(exitMessages[k])
+	}
+	writeCard()
+	os.Exit(code)
+}
+
+// exitMessages are printed once the terminal has been put back, so they can
+// be copied. Setting the same key again replaces the message. Records saved
+// in the background set them too, so they're kept behind a lock.
+var (
+	exitMessages = map[string]string{}
+	exitOrder    []string
+	exitMu       sync.Mutex
+)
+
+func setExitMessage(key, msg string) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	if _, ok := exitMessages[key]; !ok {
+		exitOrder = append(exitOrder, key)
+	}
+	exitMessages[key] = msg
+}
+
+// run plays the game and returns the exit code. Every way out of the game,
+// including a panic or a signal, goes back through here so that the terminal
+// gets restored.
+func run() (code int) {
 	// XXX - Wait a bit until the terminal is properly initialized
 	time.Sleep(500 * time.Millisecond)
 
 	err := tm.Init()
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "couldn't initialize the terminal: %v\n", err)
+		return 1
 	}
-	defer tm.Close()
+
+	defer func() {
+		r := recover()
+		tm.Close()
+		if r == nil {
+			return
+		}
+		fn, err := writeCrashReport(r, debug.Stack())
+		fmt.Fprintf(os.Stderr, "bombitron crashed: %v\n", r)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "a crash report was written to %s\n", fn)
+		}
+		code = 2
+	}()
 
 	w, h := tm.Size()
 	Width = w * 2
@@ -69,18 +171,35 @@ func main() {
 
 	gameGrid = NewGrid()
 	titleOverlay := NewTitleOverlay()
+	defer func() {
+		if replayViewer != nil {
+			return
+		}
+		if gameGrid.State == GAME_RUNNING {
+			gameGrid.SaveReplay()
+		}
+		saveGame(gameGrid)
+	}()
 
 	eventQueue := make(chan tm.Event)
-	go func() {
+	goSafe(func() {
 		for {
 			eventQueue <- tm.PollEvent()
 		}
-	}()
+	})
 
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := time.NewTicker(timerInterval())
 	done := make(chan bool)
+	defer func() {
+		ticker.Stop()
+		close(done)
+	}()
 
-	go func() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
+	defer signal.Stop(sigs)
+
+	goSafe(func() {
 		for {
 			select {
 			case <-done:
@@ -89,54 +208,211 @@ func main() {
 				allSprites.TriggerEvent("UpdateTimer")
 			}
 		}
-	}()
+	})
 
 mainloop:
 	for {
 		tm.Clear(tm.Color187, tm.Color187)
 
 		select {
+		case <-sigs:
+			break mainloop
+		case r := <-crashes:
+			panic(r)
 		case ev := <-eventQueue:
 			if ev.Type == tm.EventKey {
-				if ev.Key == tm.KeyCtrlC || ev.Key == tm.KeyEsc || ev.Ch == 'q' {
+				if ev.Key == tm.KeyCtrlC {
+					break mainloop
+				} else if titleOverlay.Notice != nil && titleOverlay.Notice.Visible {
+					titleOverlay.Notice.Close()
+				} else if titleOverlay.Scores != nil && titleOverlay.Scores.Visible {
+					titleOverlay.Scores.HandleKey(ev)
+				} else if titleOverlay.Stats != nil && titleOverlay.Stats.Visible {
+					titleOverlay.Stats.HandleKey(ev)
+				} else if titleOverlay.Puzzles != nil && titleOverlay.Puzzles.Visible {
+					if p := titleOverlay.Puzzles.HandleKey(ev); p != nil {
+						titleOverlay.playPuzzle(p)
+					}
+				} else if titleOverlay.Code != nil && titleOverlay.Code.Visible {
+					if gc := titleOverlay.Code.HandleKey(ev); gc != nil {
+						gameGrid.StartCode(gc)
+						allSprites.TriggerEvent("SelectorClicked")
+					}
+				} else if ev.Key == tm.KeyEsc || ev.Ch == 'q' {
 					break mainloop
+				} else if replayViewer != nil {
+					replayViewer.HandleKey(ev)
+				} else if boardEditor != nil {
+					boardEditor.HandleKey(ev)
+				} else if gameGrid.Summary.Visible && gameGrid.Summary.HandleKey(ev.Ch) {
+					continue
+				} else if ev.Ch == 'p' || ev.Key == tm.KeySpace {
+					gameGrid.TogglePause()
+				} else if ev.Ch == 'r' {
+					gameGrid.Record = true
+				} else if ev.Ch == 'a' {
+					gameGrid.EnableAnalysis()
+				} else if ev.Ch == 'u' || ev.Key == tm.KeyCtrlZ {
+					gameGrid.Undo()
+				} else if ev.Key == tm.KeyCtrlY {
+					gameGrid.Redo()
+				} else if ev.Key == tm.KeyArrowUp {
+					gameGrid.Hover.MoveBy(0, -1)
+				} else if ev.Key == tm.KeyArrowDown {
+					gameGrid.Hover.MoveBy(0, 1)
+				} else if ev.Key == tm.KeyArrowLeft {
+					gameGrid.Hover.MoveBy(-1, 0)
+				} else if ev.Key == tm.KeyArrowRight {
+					gameGrid.Hover.MoveBy(1, 0)
+				} else if ev.Key == tm.KeyEnter && gameGrid.Hover.Pos != -1 {
+					gameGrid.Reveal(gameGrid.Hover.Pos)
+				} else if ev.Ch == 'f' && gameGrid.Hover.Pos != -1 {
+					gameGrid.ToggleMark(gameGrid.Hover.Pos)
+				} else if ev.Ch == 'c' && gameGrid.Hover.Pos != -1 {
+					gameGrid.Chord(gameGrid.Hover.Pos)
+				} else if ev.Ch == 'h' {
+					gameGrid.ShowHint()
+				} else if ev.Ch == 'o' {
+					gameGrid.ToggleOverlay()
+				} else if ev.Ch == 't' {
+					gameGrid.ToggleTraining()
+				} else if ev.Ch == 'x' {
+					if fn, err := gameGrid.ExportBoard(); err != nil {
+						gameGrid.HintBox.Say("board not saved, " + err.Error())
+					} else {
+						gameGrid.HintBox.Say("board saved to " + filepath.Base(fn))
+					}
+				} else if ev.Ch == 'g' {
+					settings.HoverGuides = !settings.HoverGuides
+				} else if ev.Ch == 'n' {
+					settings.HoverNeighbours = !settings.HoverNeighbours
 				}
 			} else if ev.Type == tm.EventMouse {
 				MouseX = ev.MouseX * 2
 				MouseY = ev.MouseY * 2
+				if replayViewer != nil {
+					continue
+				} else if boardEditor != nil {
+					boardEditor.HandleMouse(ev)
+					continue
+				}
+				if gameGrid.State != GAME_INIT && gameGrid.State != GAME_READY {
+					gameGrid.Hover.MoveTo(MouseX, MouseY)
+
+					face := gameGrid.Face
+					if ev.Key == tm.MouseLeft && !pointer.Down && face.HitAtPointSurface(MouseX, MouseY) {
+						face.Pressed = true
+						continue
+					} else if ev.Key == tm.MouseRelease && face.Pressed {
+						face.Pressed = false
+						if face.HitAtPointSurface(MouseX, MouseY) {
+							pointer = Pointer{}
+							gameGrid.NewGame()
+						}
+						continue
+					} else if face.Pressed {
+						continue
+					}
+				}
 				if ev.Key == tm.MouseLeft {
 					if gameGrid.State == GAME_READY {
+						if titleOverlay.Notice.Visible {
+							titleOverlay.Notice.Close()
+							continue
+						}
+						if titleOverlay.Scores.Visible || titleOverlay.Stats.Visible {
+							titleOverlay.Scores.Close()
+							titleOverlay.Stats.Close()
+							continue
+						}
+						if titleOverlay.Puzzles.Visible {
+							if !titleOverlay.Puzzles.HitAtPointSurface(MouseX, MouseY) {
+								titleOverlay.Puzzles.Close()
+							} else if p := titleOverlay.Puzzles.Click(MouseX, MouseY); p != nil {
+								titleOverlay.playPuzzle(p)
+							}
+							continue
+						}
+						if titleOverlay.Code.Visible {
+							if !titleOverlay.Code.HitAtPointSurface(MouseX, MouseY) {
+								titleOverlay.Code.Close()
+							}
+							continue
+						}
 						s := titleOverlay.CheckSelectorClicked(MouseX, MouseY)
-						if s != nil {
+						if s != nil && s.Type == "scores" {
+							titleOverlay.Scores.Open()
+						} else if s != nil && s.Type == "stats" {
+							titleOverlay.Stats.Open()
+						} else if s != nil && s.Type == "edit" {
+							if e, err := NewEditor(""); err != nil {
+								titleOverlay.Notice.Open("couldn't open the editor, " + err.Error())
+							} else if err := e.Start(); err != nil {
+								titleOverlay.Notice.Open("couldn't open the editor, " + err.Error())
+							} else {
+								boardEditor = e
+								allSprites.TriggerEvent("SelectorClicked")
+							}
+						} else if s != nil && s.Type == "puzzles" {
+							titleOverlay.Puzzles.Open()
+						} else if s != nil && s.Type == "code" {
+							titleOverlay.Code.Open("", "")
+						} else if s != nil && s.Type == "daily" {
+							s.Armed = !s.Armed
+							allSprites.TriggerEvent("MouseMove")
+						} else if s != nil && s.Type == "resume" {
+							if err := resumeGame(gameGrid); err != nil {
+								titleOverlay.Notice.Open("couldn't resume the game, " + err.Error())
+								if !haveSaveGame() {
+									titleOverlay.RemoveSelector(s)
+								}
+							} else {
+								allSprites.MoveToTop(gameGrid.Kaboom)
+								allSprites.MoveToTop(gameGrid.Summary)
+								allSprites.TriggerEvent("SelectorClicked")
+							}
+						} else if s != nil && titleOverlay.Daily() {
+							gameGrid.StartDaily(s.Type, s.BombRate)
+							allSprites.TriggerEvent("SelectorClicked")
+						} else if s != nil {
 							gameGrid.TotalBombs = int(math.Round(float64(gameGrid.Width) * float64(gameGrid.Height) * s.BombRate))
+							gameGrid.Difficulty = s.Type
+							gameGrid.Seed = rand.Int63()
+							gameGrid.Record = settings.RecordReplays
 							gameGrid.State = GAME_STARTED
 							allSprites.TriggerEvent("SelectorClicked")
 						}
 					} else if gameGrid.State == GAME_RUNNING || gameGrid.State == GAME_STARTED {
-						t := gameGrid.FindTileClicked(MouseX, MouseY)
-						if t != nil {
-							pos := gameGrid.GetTilePos(t)
-							if gameGrid.State == GAME_STARTED {
-								gameGrid.PlaceBombs(t)
-								allSprites.MoveToTop(gameGrid.Super)
-							}
-							gameGrid.RevealTileAtPos(pos)
-							gameGrid.CheckGameOver()
+						if pointer.Down {
+							pointer.Move(gameGrid, MouseX, MouseY)
+						} else {
+							pointer.Press(gameGrid, MouseX, MouseY, false)
 						}
 					} else if gameGrid.State == GAME_OVER {
-						allSprites.TriggerEvent("ReturnToGrid")
+						if gameGrid.Summary.Visible && gameGrid.Summary.HitAtPointSurface(MouseX, MouseY) {
+							gameGrid.Summary.Click(MouseX, MouseY)
+						} else {
+							allSprites.TriggerEvent("ReturnToGrid")
+						}
 					}
 				} else if ev.Key == tm.MouseRight {
+					t := gameGrid.FindTileClicked(MouseX, MouseY)
+					if t != nil {
+						gameGrid.ToggleMark(gameGrid.GetTilePos(t))
+					}
+				} else if ev.Key == tm.MouseMiddle {
 					if gameGrid.State == GAME_RUNNING {
-						t := gameGrid.FindTileClicked(MouseX, MouseY)
-						if t != nil && t.Covered {
-							t.SetFlag()
-							gameGrid.CheckGameOver()
+						if pointer.Down {
+							pointer.Move(gameGrid, MouseX, MouseY)
+						} else {
+							pointer.Press(gameGrid, MouseX, MouseY, true)
 						}
 					}
 				} else if ev.Key == tm.MouseRelease {
 					if gameGrid.State == GAME_READY {
 						allSprites.TriggerEvent("MouseMove")
+					} else if pointer.Down {
+						pointer.Release(gameGrid, MouseX, MouseY)
 					}
 				}
 			} else if ev.Type == tm.EventResize {
@@ -151,15 +427,54 @@ mainloop:
 
 				if gameGrid.State == GAME_INIT && Width > 80 && Height > 40 {
 					gameGrid.SetReady()
+					if replayViewer != nil {
+						if err := replayViewer.Start(); err != nil {
+							setExitMessage("replay", err.Error())
+							break mainloop
+						}
+						continue
+					}
 					gameGrid.SetSize(Width/8, (Height-HEADER_OFFSET)/8)
+					if boardEditor != nil {
+						if err := boardEditor.Start(); err != nil {
+							setExitMessage("board", err.Error())
+							break mainloop
+						}
+						continue
+					}
 					titleOverlay.SetGameReady()
 					titleOverlay.MoveToTop()
+
+					if playCode != "" {
+						gc, _ := ParseGameCode(playCode)
+						if gc.Fits() {
+							gameGrid.StartCode(gc)
+							allSprites.TriggerEvent("SelectorClicked")
+						} else {
+							titleOverlay.Code.Open(playCode, "board too big for this terminal")
+						}
+						playCode = ""
+					} else if playBoard != nil {
+						if !playBoard.Fits() {
+							setExitMessage("board", fmt.Sprintf("the terminal is too small for a %dx%d board", playBoard.Width, playBoard.Height))
+						} else if err := gameGrid.StartBoard(playBoard); err != nil {
+							setExitMessage("board", "couldn't start the board, "+err.Error())
+							titleOverlay.Notice.Open("couldn't start the board, " + err.Error())
+						} else {
+							allSprites.TriggerEvent("SelectorClicked")
+						}
+						playBoard = nil
+					}
 				}
 			}
 		default:
+			if replayViewer != nil {
+				replayViewer.Tick()
+			}
 			allSprites.Update()
 			allSprites.Render()
 			time.Sleep(60 * time.Millisecond)
 		}
 	}
+	return 0
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+const HINT_TIMEOUT = 80
+
+// A Hint is one tile the solver is sure about, or the safest guess if it
+// isn't sure about any, along with why.
+type Hint struct {
+	Pos  int
+	Mine bool
+	Sure bool
+	Text string
+}
+
+// HintBox points out a hinted tile and says why, in place of the HUD line
+// until it times out or the tile changes.
+type HintBox struct {
+	sprite.BaseSprite
+	font    *sprite.Font
+	Hint    *Hint
+	Box     *HintMarker
+	Timer   int
+	covered bool
+	flagged bool
+}
+
+// HintMarker is the box drawn around the hinted tile.
+type HintMarker struct {
+	sprite.BaseSprite
+}
+
+// tileName is how a tile is written in a hint, as column,row counting from 1.
+func (g *Grid) tileName(pos int) string {
+	return fmt.Sprintf("%d,%d", pos%g.Width+1, pos/g.Width+1)
+}
+
+// explain says which rule found a deduction, in words.
+func (g *Grid) explain(d Deduction) string {
+	number := func(pos int) string {
+		return fmt.Sprintf("the %d at %s", g.Tiles[pos].BombCount, g.tileName(pos))
+	}
+	switch d.Rule {
+	case RULE_SATISFIED:
+		return number(d.From[0]) + " is already satisfied"
+	case RULE_FULL:
+		return number(d.From[0]) + " needs all of its tiles"
+	case RULE_PAIR:
+		return "compare " + number(d.From[0]) + " with " + number(d.From[1])
+	}
+	if d.Mine {
+		return "every covered tile is a mine"
+	}
+	return "all the mines have been found"
+}
+
+// FindHint asks the solver for a tile it can be sure of. Safe tiles come
+// first, then mines which haven't been flagged yet. Mines which are already
+// flagged are taken as known so the solver can carry on from them. If
+// there's nothing sure, the covered tile least likely to be a mine is
+// suggested instead.
+func (g *Grid) FindHint() *Hint {
+	s := NewGridSolver(g)
+	for {
+		ds := s.Step()
+		if len(ds) == 0 {
+			break
+		}
+		for _, d := range ds {
+			if !d.Mine {
+				return &Hint{Pos: d.Pos, Sure: true, Text: g.explain(d)}
+			}
+		}
+		for _, d := range ds {
+			if !g.Tiles[d.Pos].HaveFlag {
+				return &Hint{Pos: d.Pos, Mine: true, Sure: true, Text: g.explain(d)}
+			}
+			s.Known[d.Pos] = true
+		}
+	}
+
+	best, lowest := -1, math.Inf(1)
+	for pos, p := range s.Probabilities() {
+		if p >= 0 && !s.Known[pos] && !g.Tiles[pos].HaveFlag && p < lowest {
+			best, lowest = pos, p
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return &Hint{
+		Pos:  best,
+		Text: fmt.Sprintf("no sure moves, safest is %s at %.0f%%", g.tileName(best), lowest*100),
+	}
+}
+
+// ShowHint works out a hint and points it out. Every hint is counted, so the
+// game is marked as hinted in the scores.
+func (g *Grid) ShowHint() {
+	if g.State != GAME_RUNNING || g.Paused {
+		return
+	}
+	h := g.FindHint()
+	if h == nil {
+		return
+	}
+	g.Hints++
+	g.RecordMove(MOVE_HINT, h.Pos)
+	g.HintBox.Show(h)
+}
+
+func NewHintBox() *HintBox {
+	b := &HintBox{BaseSprite: sprite.BaseSprite{
+		X:       4,
+		Y:       HUD_Y,
+		Visible: false},
+		font: sprite.NewPakuFont(),
+		Box:  NewHintMarker(),
+	}
+	b.Init()
+
+	b.RegisterEvent("NewGame", func() {
+		b.Hide()
+	})
+
+	b.RegisterEvent("Undo", func() {
+		b.Hide()
+	})
+
+	return b
+}
+
+func (b *HintBox) Show(h *Hint) {
+	b.Hint = h
+	b.Timer = HINT_TIMEOUT
+
+	c := 'o'
+	if h.Sure && h.Mine {
+		c = 'r'
+	} else if h.Sure {
+		c = 'b'
+	}
+	surf := textSurface(b.font, h.Text, c)
+	b.BlockCostumes = []*sprite.Surface{&surf}
+	b.SetCostume(0)
+	b.Visible = true
+	b.Box.Visible = false
+	if h.Pos < 0 {
+		return
+	}
+
+	t := gameGrid.Tiles[h.Pos]
+	b.covered = t.Covered
+	b.flagged = t.HaveFlag
+	b.Box.Draw(c)
+	b.Box.X = t.GridX
+	b.Box.Y = t.GridY
+	b.Box.Visible = true
+	allSprites.MoveToTop(b.Box)
+}
+
+// Say shows a message on its own, without pointing at a tile.
+func (b *HintBox) Say(text string) {
+	b.Show(&Hint{Pos: -1, Text: text})
+}
+
+func (b *HintBox) Hide() {
+	b.Hint = nil
+	b.Visible = false
+	b.Box.Visible = false
+}
+
+// Update puts the hint away once it runs out of time, or as soon as the
+// hinted tile is opened or flagged or the game ends. A message on its own
+// stays up until it times out.
+func (b *HintBox) Update() {
+	if b.Hint == nil {
+		return
+	}
+	g := gameGrid
+	b.Timer--
+	if b.Timer <= 0 || g.Paused || b.Hint.Pos >= len(g.Tiles) {
+		b.Hide()
+		return
+	}
+	if b.Hint.Pos >= 0 {
+		t := g.Tiles[b.Hint.Pos]
+		if g.State != GAME_RUNNING || t.Covered != b.covered || t.HaveFlag != b.flagged {
+			b.Hide()
+		}
+	}
+}
+
+func NewHintMarker() *HintMarker {
+	m := &HintMarker{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+	}
+	m.Init()
+	return m
+}
+
+func (m *HintMarker) Draw(c rune) {
+	surf := sprite.NewSurface(TILE_WIDTH, TILE_HEIGHT, true)
+	surf.Rectangle(0, 0, TILE_WIDTH-1, TILE_HEIGHT-1, c)
+	m.BlockCostumes = []*sprite.Surface{&surf}
+	m.SetCostume(0)
+}
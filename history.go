@@ -0,0 +1,136 @@
+package main
+
+// TileState is the part of a tile which changes during a game.
+type TileState struct {
+	Covered      bool
+	HaveFlag     bool
+	HaveQuestion bool
+	Exploded     bool
+}
+
+// A Snapshot is the state of the whole board between two moves.
+type Snapshot struct {
+	Tiles     []TileState
+	State     GameState
+	Remaining int
+	Result    *Result
+}
+
+// History keeps every board state of a game so that moves can be undone and
+// redone in analysis mode.
+type History struct {
+	Undo []Snapshot
+	Redo []Snapshot
+}
+
+func (g *Grid) Snapshot() Snapshot {
+	s := Snapshot{
+		Tiles:     make([]TileState, len(g.Tiles)),
+		State:     g.State,
+		Remaining: g.FlagsRemaining.Remaining,
+		Result:    g.Result,
+	}
+	for cnt, t := range g.Tiles {
+		s.Tiles[cnt] = TileState{
+			Covered:      t.Covered,
+			HaveFlag:     t.HaveFlag,
+			HaveQuestion: t.HaveQuestion,
+			Exploded:     t.Exploded,
+		}
+	}
+	return s
+}
+
+// RestoreSnapshot puts the board back the way it was. Tiles which bounced
+// away after a win are returned to the grid.
+func (g *Grid) RestoreSnapshot(s Snapshot) {
+	g.clearSparks()
+	for cnt, ts := range s.Tiles {
+		t := g.Tiles[cnt]
+		t.Covered = ts.Covered
+		t.HaveFlag = ts.HaveFlag
+		t.HaveQuestion = ts.HaveQuestion
+		t.Exploded = ts.Exploded
+		t.RevealIn = 0
+		t.VX = 0
+		t.VY = 0
+		t.X = t.GridX
+		t.Y = t.GridY
+		t.Redraw()
+	}
+
+	if s.State == GAME_OVER && s.Result != nil && !s.Result.Won {
+		g.RevealMines(-1, false)
+	}
+
+	wasOver := g.State == GAME_OVER
+	g.State = s.State
+	g.Result = s.Result
+	g.FlagsRemaining.Remaining = s.Remaining
+	allSprites.TriggerEvent("ShowFlagsRemaining")
+
+	if wasOver && g.State == GAME_RUNNING {
+		g.TimerElapsed.Watch.Continue()
+		allSprites.TriggerEvent("Undo")
+	} else if !wasOver && g.State == GAME_OVER {
+		g.TimerElapsed.Watch.Stop()
+	}
+}
+
+// checkpoint is called before each move so that it can be undone later. Any
+// moves which were undone can't be redone after a new move is made. History
+// is only kept in analysis mode, so undo reaches back to when it was turned on.
+func (g *Grid) checkpoint() {
+	if g.State != GAME_RUNNING {
+		return
+	}
+	g.pushUndo(g.Snapshot())
+}
+
+func (g *Grid) pushUndo(s Snapshot) {
+	if !g.Analysis {
+		return
+	}
+	g.History.Undo = append(g.History.Undo, s)
+	g.History.Redo = nil
+}
+
+// EnableAnalysis turns on undo and redo for the rest of the game. The game is
+// marked as assisted so it won't count towards any records.
+func (g *Grid) EnableAnalysis() {
+	if g.State != GAME_RUNNING && g.State != GAME_STARTED {
+		return
+	}
+	g.Analysis = true
+	g.Assisted = true
+	allSprites.TriggerEvent("ShowAnalysis")
+}
+
+func (g *Grid) Undo() {
+	if !g.Analysis || g.Paused || len(g.History.Undo) == 0 {
+		return
+	}
+
+	s := g.History.Undo[len(g.History.Undo)-1]
+	g.History.Undo = g.History.Undo[:len(g.History.Undo)-1]
+	g.History.Redo = append(g.History.Redo, g.Snapshot())
+
+	g.RecordMove(MOVE_UNDO, -1)
+	g.RestoreSnapshot(s)
+}
+
+func (g *Grid) Redo() {
+	if !g.Analysis || g.Paused || len(g.History.Redo) == 0 {
+		return
+	}
+
+	s := g.History.Redo[len(g.History.Redo)-1]
+	g.History.Redo = g.History.Redo[:len(g.History.Redo)-1]
+	g.History.Undo = append(g.History.Undo, g.Snapshot())
+
+	g.RecordMove(MOVE_REDO, -1)
+	g.RestoreSnapshot(s)
+	if g.State == GAME_OVER {
+		g.SaveReplay()
+	}
+}
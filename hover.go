@@ -0,0 +1,123 @@
+package main
+
+import (
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+// Hover outlines the covered tile under the mouse or the keyboard cursor,
+// whichever moved last. It can also outline the tiles around it and draw
+// guides along its row and column to help on big boards.
+//
+// Mouse motion comes through termbox as MouseRelease events, since the
+// terminal is put in any-event mouse tracking when the mouse is turned on.
+type Hover struct {
+	sprite.BaseSprite
+	Pos   int
+	drawn hoverState
+}
+
+// hoverState is everything the hover's picture depends on, so it only gets
+// redrawn when something changes.
+type hoverState struct {
+	pos        int
+	covered    bool
+	neighbours bool
+	guides     bool
+}
+
+func NewHover() *Hover {
+	h := &Hover{BaseSprite: sprite.BaseSprite{
+		X:       0,
+		Y:       HEADER_OFFSET,
+		Visible: false},
+		Pos: -1,
+	}
+	h.Init()
+
+	h.RegisterEvent("NewGame", func() {
+		h.Pos = -1
+	})
+
+	return h
+}
+
+// MoveTo puts the hover over the tile at the mouse pointer.
+func (h *Hover) MoveTo(x, y int) {
+	h.Pos = -1
+	if t := gameGrid.FindTileClicked(x, y); t != nil {
+		h.Pos = gameGrid.GetTilePos(t)
+	}
+}
+
+// MoveBy moves the keyboard cursor, starting from the middle of the board.
+func (h *Hover) MoveBy(dc, dr int) {
+	g := gameGrid
+	if h.Pos == -1 {
+		h.Pos = (g.Height/2)*g.Width + g.Width/2
+		return
+	}
+	r := h.Pos/g.Width + dr
+	c := h.Pos%g.Width + dc
+	if r < 0 || c < 0 || r >= g.Height || c >= g.Width {
+		return
+	}
+	h.Pos = r*g.Width + c
+}
+
+func (h *Hover) Update() {
+	g := gameGrid
+	if h.Pos < 0 || h.Pos >= len(g.Tiles) || g.Paused || (g.State != GAME_RUNNING && g.State != GAME_STARTED) {
+		h.Visible = false
+		return
+	}
+	h.Visible = true
+
+	state := hoverState{
+		pos:        h.Pos,
+		covered:    g.Tiles[h.Pos].Covered,
+		neighbours: settings.HoverNeighbours,
+		guides:     settings.HoverGuides,
+	}
+	if state == h.drawn && len(h.BlockCostumes) > 0 {
+		return
+	}
+	h.drawn = state
+
+	surf := sprite.NewSurface(g.Width*TILE_WIDTH, g.Height*TILE_HEIGHT, true)
+	r, c := h.Pos/g.Width, h.Pos%g.Width
+	x0, y0 := c*TILE_WIDTH, r*TILE_HEIGHT
+
+	if settings.HoverGuides {
+		cy := y0 + TILE_HEIGHT/2
+		for x := 0; x < surf.Width; x += 4 {
+			surf.Blocks[cy][x] = 'l'
+		}
+		cx := x0 + TILE_WIDTH/2
+		for y := 0; y < surf.Height; y += 4 {
+			surf.Blocks[y][cx] = 'l'
+		}
+	}
+
+	if settings.HoverNeighbours {
+		nx0, ny0 := x0-TILE_WIDTH, y0-TILE_HEIGHT
+		nx1, ny1 := x0+2*TILE_WIDTH-1, y0+2*TILE_HEIGHT-1
+		surf.Rectangle(clamp(nx0, 0, surf.Width-1), clamp(ny0, 0, surf.Height-1),
+			clamp(nx1, 0, surf.Width-1), clamp(ny1, 0, surf.Height-1), 'o')
+	}
+
+	if state.covered {
+		surf.Rectangle(x0, y0, x0+TILE_WIDTH-1, y0+TILE_HEIGHT-1, 'y')
+	}
+
+	h.BlockCostumes = []*sprite.Surface{&surf}
+	h.SetCostume(0)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	} else if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+const (
+	HUD_Y   = 10
+	HUD_GAP = 8
+)
+
+// A hudWidget is one piece of the HUD line. The short form is used when
+// there isn't enough room for everything at full length.
+type hudWidget struct {
+	Full  func(g *Grid) string
+	Short func(g *Grid) string
+}
+
+var hudWidgets = map[string]hudWidget{
+	"difficulty": {
+		Full: func(g *Grid) string { return g.Difficulty },
+		Short: func(g *Grid) string {
+			if g.Difficulty == "" {
+				return ""
+			}
+			return g.Difficulty[:1]
+		},
+	},
+	"board": {
+		Full: func(g *Grid) string {
+			return fmt.Sprintf("%dx%d %d mines", g.Width, g.Height, g.TotalBombs)
+		},
+		Short: func(g *Grid) string {
+			return fmt.Sprintf("%dx%d/%d", g.Width, g.Height, g.TotalBombs)
+		},
+	},
+	"seed": {
+		Full: func(g *Grid) string { return fmt.Sprintf("seed %d", g.Seed) },
+	},
+	"3bv": {
+		Full: func(g *Grid) string {
+			total, solved := g.BoardValue()
+			if total == 0 {
+				return "3bv -"
+			}
+			return fmt.Sprintf("3bv %d/%d", solved, total)
+		},
+		Short: func(g *Grid) string {
+			total, _ := g.BoardValue()
+			return fmt.Sprintf("3bv %d", total)
+		},
+	},
+	"speed": {
+		Full:  func(g *Grid) string { return fmt.Sprintf("3bv/s %.2f", g.Speed()) },
+		Short: func(g *Grid) string { return fmt.Sprintf("%.2f/s", g.Speed()) },
+	},
+	"efficiency": {
+		Full:  func(g *Grid) string { return fmt.Sprintf("eff %d%%", g.Efficiency()) },
+		Short: func(g *Grid) string { return fmt.Sprintf("%d%%", g.Efficiency()) },
+	},
+}
+
+// hudOrder is the order widgets are shown in by default.
+var hudOrder = []string{"difficulty", "board", "seed", "3bv", "speed", "efficiency"}
+
+// parseHUD turns a comma separated list of widgets into a list of names.
+// Any it doesn't know are left out and complained about.
+func parseHUD(s string) ([]string, error) {
+	names := []string{}
+	var err error
+	for _, n := range strings.Split(s, ",") {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "" {
+			continue
+		}
+		if _, ok := hudWidgets[n]; !ok {
+			err = fmt.Errorf("unknown HUD widget %q (choose from %s)", n, strings.Join(hudOrder, ", "))
+			continue
+		}
+		names = append(names, n)
+	}
+	return names, err
+}
+
+// HUD is the line of game details and live stats under the flag count and
+// the timer. Widgets are dropped from the end of the line, after trying
+// their short forms, when the terminal is too narrow to fit them all.
+type HUD struct {
+	sprite.BaseSprite
+	font  *sprite.Font
+	drawn string
+}
+
+func NewHUD() *HUD {
+	h := &HUD{BaseSprite: sprite.BaseSprite{
+		X:       4,
+		Y:       HUD_Y,
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	h.Init()
+	return h
+}
+
+// layout picks which widgets to show and how, to fit in the given width.
+// Each glyph in the font is four blocks wide.
+func (h *HUD) layout(g *Grid, width int) []string {
+	full := []string{}
+	short := []string{}
+	for _, n := range settings.HUD {
+		w := hudWidgets[n]
+		s := w.Full(g)
+		if s == "" {
+			continue
+		}
+		full = append(full, s)
+		if w.Short != nil {
+			short = append(short, w.Short(g))
+		} else {
+			short = append(short, s)
+		}
+	}
+
+	size := func(parts []string) int {
+		n := 0
+		for _, p := range parts {
+			n += len(p)*4 + HUD_GAP
+		}
+		return n - HUD_GAP
+	}
+
+	parts := append([]string{}, full...)
+	for cnt := len(parts) - 1; cnt >= 0 && size(parts) > width; cnt-- {
+		parts[cnt] = short[cnt]
+	}
+	for len(parts) > 0 && size(parts) > width {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+func (h *HUD) Update() {
+	g := gameGrid
+	if len(settings.HUD) == 0 || g.HintBox.Visible || (g.State != GAME_STARTED && g.State != GAME_RUNNING && g.State != GAME_OVER) {
+		h.Visible = false
+		return
+	}
+	h.Visible = true
+
+	parts := h.layout(g, Width-8)
+	s := strings.Join(parts, strings.Repeat(" ", HUD_GAP/4))
+	if s == "" {
+		h.Visible = false
+		return
+	}
+	if s == h.drawn && len(h.BlockCostumes) > 0 {
+		return
+	}
+	h.drawn = s
+
+	surf := sprite.NewSurfaceFromString(h.font.BuildString(s), true)
+	h.BlockCostumes = []*sprite.Surface{&surf}
+	h.SetCostume(0)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sort"
+)
+
+const (
+	LOSS_FIRST_MOVE = "first_move"
+	LOSS_LATE_GUESS = "late_guess"
+
+	histogramBuckets = 10
+)
+
+// A HistogramBucket counts the wins which took from MinMs up to MaxMs.
+type HistogramBucket struct {
+	MinMs int64 `json:"min_ms"`
+	MaxMs int64 `json:"max_ms"`
+	Count int   `json:"count"`
+}
+
+// LifetimeStats sums up every unassisted game played on one difficulty.
+// Times only count games which were won. Games played with hints count like
+// any other, and how many there were and how many hints they took is kept
+// alongside.
+type LifetimeStats struct {
+	Difficulty      string            `json:"difficulty"`
+	Played          int               `json:"played"`
+	Won             int               `json:"won"`
+	WinRate         float64           `json:"win_rate"`
+	CurrentStreak   int               `json:"current_streak"`
+	BestStreak      int               `json:"best_streak"`
+	AverageMs       int64             `json:"average_ms"`
+	MedianMs        int64             `json:"median_ms"`
+	FirstMoveLosses int               `json:"first_move_losses"`
+	LateGuessLosses int               `json:"late_guess_losses"`
+	HintedGames     int               `json:"hinted_games"`
+	Hints           int               `json:"hints"`
+	Histogram       []HistogramBucket `json:"histogram"`
+}
+
+// lossCause works out why a game was lost. Losing on the very first click
+// is bad luck, and anything after that was a guess which went wrong. Games
+// which started part way through, like resumed games and puzzles, only know
+// the moves since then, so they never count as lost on the first click.
+func lossCause(g *Grid) string {
+	if g.Result == nil || g.Result.Won {
+		return ""
+	}
+	left, _, chord := g.ClickCounts()
+	if left+chord <= 1 && g.StartCells == nil {
+		return LOSS_FIRST_MOVE
+	}
+	return LOSS_LATE_GUESS
+}
+
+// NewLifetimeStats adds up the games for a difficulty. Scores are kept in
+// the order they were played, which is what the streaks go by.
+func NewLifetimeStats(scores []Score, difficulty string) LifetimeStats {
+	ls := LifetimeStats{Difficulty: difficulty}
+	times := []int64{}
+	streak := 0
+
+	for _, s := range scores {
+		if s.Difficulty != difficulty || s.Assisted {
+			continue
+		}
+		ls.Played++
+		if s.Hints > 0 {
+			ls.HintedGames++
+			ls.Hints += s.Hints
+		}
+		if s.Won {
+			ls.Won++
+			times = append(times, s.ElapsedMs)
+			streak++
+			if streak > ls.BestStreak {
+				ls.BestStreak = streak
+			}
+			continue
+		}
+
+		streak = 0
+		switch s.LossCause {
+		case LOSS_FIRST_MOVE:
+			ls.FirstMoveLosses++
+		case LOSS_LATE_GUESS:
+			ls.LateGuessLosses++
+		}
+	}
+	ls.CurrentStreak = streak
+
+	if ls.Played > 0 {
+		ls.WinRate = float64(ls.Won) * 100 / float64(ls.Played)
+	}
+	if len(times) == 0 {
+		return ls
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	var total int64
+	for _, t := range times {
+		total += t
+	}
+	ls.AverageMs = total / int64(len(times))
+	if n := len(times); n%2 == 1 {
+		ls.MedianMs = times[n/2]
+	} else {
+		ls.MedianMs = (times[n/2-1] + times[n/2]) / 2
+	}
+	ls.Histogram = histogram(times, histogramBuckets)
+	return ls
+}
+
+// histogram splits sorted times into evenly sized buckets between the
+// fastest and the slowest.
+func histogram(times []int64, buckets int) []HistogramBucket {
+	lo, hi := times[0], times[len(times)-1]
+	size := (hi - lo + int64(buckets)) / int64(buckets)
+	if size == 0 {
+		size = 1
+	}
+
+	h := make([]HistogramBucket, buckets)
+	for cnt := range h {
+		h[cnt].MinMs = lo + int64(cnt)*size
+		h[cnt].MaxMs = h[cnt].MinMs + size
+	}
+	for _, t := range times {
+		n := int((t - lo) / size)
+		if n >= buckets {
+			n = buckets - 1
+		}
+		h[n].Count++
+	}
+	return h
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestLossCause(t *testing.T) {
+	tests := []struct {
+		name     string
+		moves    []MoveKind
+		restored bool
+		want     string
+	}{
+		{"first click", []MoveKind{MOVE_REVEAL}, false, LOSS_FIRST_MOVE},
+		{"later guess", []MoveKind{MOVE_REVEAL, MOVE_FLAG, MOVE_REVEAL}, false, LOSS_LATE_GUESS},
+		{"first click after a resume", []MoveKind{MOVE_REVEAL}, true, LOSS_LATE_GUESS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrid("*#", "..")
+			g.Result = &Result{}
+			for _, k := range tt.moves {
+				g.Moves = append(g.Moves, Move{Kind: k, Pos: 0})
+			}
+			if tt.restored {
+				g.StartCells = []string{"##", ".."}
+			}
+			if got := lossCause(g); got != tt.want {
+				t.Errorf("lossCause() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
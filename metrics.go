@@ -0,0 +1,116 @@
+package main
+
+// 3BV (Bechtel's Board Benchmark Value) is the fewest left clicks needed to
+// clear a board without chording. Each opening takes one click, and so does
+// each number which isn't on the edge of an opening.
+
+// BoardValue works out the 3BV of the board and how much of it has been
+// cleared so far. It's zero until the mines have been laid.
+func (g *Grid) BoardValue() (total int, solved int) {
+	if g.State != GAME_RUNNING && g.State != GAME_OVER {
+		return 0, 0
+	}
+
+	seen := make([]bool, len(g.Tiles))
+	for cnt, t := range g.Tiles {
+		if seen[cnt] || t.HaveBomb || t.BombCount > 0 {
+			continue
+		}
+
+		// flood out across the opening along with the numbers around it
+		total++
+		opened := false
+		seen[cnt] = true
+		queue := []int{cnt}
+		for len(queue) > 0 {
+			pos := queue[0]
+			queue = queue[1:]
+			if g.Tiles[pos].BombCount > 0 {
+				continue
+			}
+			if !g.Tiles[pos].Covered {
+				opened = true
+			}
+			for _, n := range g.Neighbours(pos) {
+				if !seen[n] && !g.Tiles[n].HaveBomb {
+					seen[n] = true
+					queue = append(queue, n)
+				}
+			}
+		}
+		if opened {
+			solved++
+		}
+	}
+
+	for cnt, t := range g.Tiles {
+		if seen[cnt] || t.HaveBomb {
+			continue
+		}
+		total++
+		if !t.Covered {
+			solved++
+		}
+	}
+	return total, solved
+}
+
+// ClickCounts splits the moves which took a click on the board into left
+// clicks, right clicks and chords.
+func (g *Grid) ClickCounts() (left, right, chord int) {
+	for _, m := range g.Moves {
+		switch m.Kind {
+		case MOVE_REVEAL:
+			left++
+		case MOVE_FLAG, MOVE_QUESTION, MOVE_UNMARK:
+			right++
+		case MOVE_CHORD:
+			chord++
+		}
+	}
+	return left, right, chord
+}
+
+func (g *Grid) Clicks() int {
+	left, right, chord := g.ClickCounts()
+	return left + right + chord
+}
+
+// Cleared is the percentage of safe tiles which have been uncovered.
+func (g *Grid) Cleared() int {
+	safe, open := 0, 0
+	for _, t := range g.Tiles {
+		if t.HaveBomb {
+			continue
+		}
+		safe++
+		if !t.Covered {
+			open++
+		}
+	}
+	if safe == 0 {
+		return 0
+	}
+	return open * 100 / safe
+}
+
+// Speed is the 3BV cleared per second so far.
+func (g *Grid) Speed() float64 {
+	_, solved := g.BoardValue()
+	secs := g.TimerElapsed.Watch.Elapsed().Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(solved) / secs
+}
+
+// Efficiency is the 3BV cleared as a percentage of the clicks it took. It
+// can go over 100 when chording saves clicks.
+func (g *Grid) Efficiency() int {
+	clicks := g.Clicks()
+	if clicks == 0 {
+		return 0
+	}
+	_, solved := g.BoardValue()
+	return solved * 100 / clicks
+}
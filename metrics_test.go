@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+// testGrid lays out a board from rows of '*' for a mine, '#' for a covered
+// safe tile and '.' for an opened one.
+func testGrid(rows ...string) *Grid {
+	g := &Grid{State: GAME_RUNNING, Width: len(rows[0]), Height: len(rows)}
+	for _, row := range rows {
+		for _, ch := range row {
+			t := &Tile{HaveBomb: ch == '*', Covered: ch != '.'}
+			if t.HaveBomb {
+				g.TotalBombs++
+			}
+			g.Tiles = append(g.Tiles, t)
+		}
+	}
+	for cnt := range g.Tiles {
+		for _, n := range g.Neighbours(cnt) {
+			if g.Tiles[n].HaveBomb {
+				g.Tiles[cnt].BombCount++
+			}
+		}
+	}
+	return g
+}
+
+func TestBoardValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		rows          []string
+		total, solved int
+	}{
+		// one opening takes in the whole board
+		{"one opening", []string{"####", "####", "###*"}, 1, 0},
+		{"one opening opened", []string{"....", "..##", "###*"}, 1, 1},
+		// no openings, so every safe tile is a click of its own
+		{"no openings", []string{"*#*", "#*#"}, 3, 0},
+		{"no openings part solved", []string{"*.*", ".*#"}, 3, 2},
+		// an opening on the left and three numbers cut off on the right
+		{"opening and lone numbers", []string{"##*#", "##*#", "##*#"}, 4, 0},
+		{"lone number opened", []string{"##*.", "##*#", "##*#"}, 4, 1},
+		{"opening a number on the edge doesn't clear the opening", []string{"##*#", "#.*#", "##*#"}, 4, 0},
+		{"opening cleared from its corner", []string{".#*#", "##*#", "##*#"}, 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrid(tt.rows...)
+			total, solved := g.BoardValue()
+			if total != tt.total || solved != tt.solved {
+				t.Errorf("BoardValue() = %d, %d, want %d, %d", total, solved, tt.total, tt.solved)
+			}
+		})
+	}
+
+	g := testGrid("####", "###*")
+	g.State = GAME_STARTED
+	if total, solved := g.BoardValue(); total != 0 || solved != 0 {
+		t.Errorf("BoardValue() before the mines are laid = %d, %d, want 0, 0", total, solved)
+	}
+}
+
+func TestEfficiency(t *testing.T) {
+	// three clicks' worth of 3BV, all of it cleared
+	rows := []string{"*.*", ".*."}
+	tests := []struct {
+		name  string
+		moves []MoveKind
+		want  int
+	}{
+		{"no clicks", nil, 0},
+		{"one click each", []MoveKind{MOVE_REVEAL, MOVE_REVEAL, MOVE_REVEAL}, 100},
+		{"flags count as clicks", []MoveKind{MOVE_FLAG, MOVE_REVEAL, MOVE_FLAG, MOVE_REVEAL, MOVE_REVEAL, MOVE_FLAG}, 50},
+		{"chords can beat 100", []MoveKind{MOVE_REVEAL, MOVE_CHORD}, 150},
+		{"pauses and hints aren't clicks", []MoveKind{MOVE_REVEAL, MOVE_PAUSE, MOVE_UNPAUSE, MOVE_HINT, MOVE_CHORD}, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrid(rows...)
+			for _, k := range tt.moves {
+				g.Moves = append(g.Moves, Move{Kind: k, Pos: 0})
+			}
+			if got := g.Efficiency(); got != tt.want {
+				t.Errorf("Efficiency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
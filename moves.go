@@ -0,0 +1,190 @@
+package main
+
+import (
+	"time"
+)
+
+type MoveKind string
+
+const (
+	MOVE_REVEAL   MoveKind = "reveal"
+	MOVE_FLAG     MoveKind = "flag"
+	MOVE_QUESTION MoveKind = "question"
+	MOVE_UNMARK   MoveKind = "unmark"
+	MOVE_CHORD    MoveKind = "chord"
+	MOVE_PAUSE    MoveKind = "pause"
+	MOVE_UNPAUSE  MoveKind = "unpause"
+	MOVE_UNDO     MoveKind = "undo"
+	MOVE_REDO     MoveKind = "redo"
+	MOVE_HINT     MoveKind = "hint"
+)
+
+// A Move is a single action the player took. T is the time since the first
+// reveal of the game. Pauses, undos and redos aren't tied to a tile, so their
+// Pos is -1.
+type Move struct {
+	Kind MoveKind      `json:"kind"`
+	Pos  int           `json:"pos"`
+	T    time.Duration `json:"t"`
+}
+
+func (g *Grid) RecordMove(kind MoveKind, pos int) {
+	g.Moves = append(g.Moves, Move{
+		Kind: kind,
+		Pos:  pos,
+		T:    g.TimerElapsed.Watch.Elapsed(),
+	})
+}
+
+// RecentMoves returns up to the last n moves of the game.
+func (g *Grid) RecentMoves(n int) []Move {
+	if len(g.Moves) <= n {
+		return g.Moves
+	}
+	return g.Moves[len(g.Moves)-n:]
+}
+
+// markKind describes the marker a tile was left with after a right click.
+func markKind(t *Tile) MoveKind {
+	if t.HaveFlag {
+		return MOVE_FLAG
+	} else if t.HaveQuestion {
+		return MOVE_QUESTION
+	}
+	return MOVE_UNMARK
+}
+
+// Reveal is a left click on a tile. The first reveal of a game lays out the
+// bombs, and clicking on a number which has already been uncovered chords it.
+func (g *Grid) Reveal(pos int) {
+	if pos < 0 || pos >= len(g.Tiles) || g.Paused {
+		return
+	}
+
+	t := g.Tiles[pos]
+	if g.State == GAME_STARTED {
+		if g.Start < 0 {
+			g.Start = pos
+		}
+		if g.Layout != nil {
+			g.LayMines(g.Layout)
+		} else {
+			g.PlaceBombs(t)
+		}
+		allSprites.MoveToTop(g.Kaboom)
+		allSprites.MoveToTop(g.Summary)
+	}
+	if g.State != GAME_RUNNING {
+		return
+	}
+
+	if !t.Covered {
+		g.Chord(pos)
+		return
+	}
+
+	g.noteGuess([]int{pos})
+	g.checkpoint()
+	g.RecordMove(MOVE_REVEAL, pos)
+	covered := g.coveredTiles()
+	g.RevealTileAtPos(pos)
+	g.ripple([]int{pos}, g.uncoveredSince(covered))
+	g.endMove()
+}
+
+// ToggleMark is a right click, which cycles a covered tile through a flag,
+// a question mark (if the rules allow them) and back to nothing.
+func (g *Grid) ToggleMark(pos int) {
+	if pos < 0 || pos >= len(g.Tiles) || g.Paused || g.State != GAME_RUNNING {
+		return
+	}
+
+	t := g.Tiles[pos]
+	if !t.Covered {
+		return
+	}
+	before := g.Snapshot()
+	t.SetFlag()
+	if t.HaveFlag == before.Tiles[pos].HaveFlag && t.HaveQuestion == before.Tiles[pos].HaveQuestion {
+		return
+	}
+	g.pushUndo(before)
+	g.RecordMove(markKind(t), pos)
+	g.endMove()
+}
+
+// Chord reveals every unflagged neighbour of an uncovered number once the
+// right number of flags have been placed around it.
+func (g *Grid) Chord(pos int) {
+	if pos < 0 || pos >= len(g.Tiles) || g.Paused || g.State != GAME_RUNNING {
+		return
+	}
+
+	t := g.Tiles[pos]
+	if t.Covered || t.BombCount == 0 {
+		return
+	}
+
+	flags := 0
+	for _, n := range g.Neighbours(pos) {
+		if g.Tiles[n].HaveFlag {
+			flags++
+		}
+	}
+	if flags != t.BombCount {
+		return
+	}
+
+	g.noteGuess(g.Neighbours(pos))
+	g.checkpoint()
+	g.RecordMove(MOVE_CHORD, pos)
+	covered := g.coveredTiles()
+	for _, n := range g.Neighbours(pos) {
+		g.RevealTileAtPos(n)
+	}
+	g.ripple(g.Neighbours(pos), g.uncoveredSince(covered))
+	g.endMove()
+}
+
+// TogglePause stops the clock and hides the board so it can't be studied
+// while the clock is stopped.
+func (g *Grid) TogglePause() {
+	if g.State != GAME_RUNNING {
+		return
+	}
+
+	g.Paused = !g.Paused
+	if g.Paused {
+		g.TimerElapsed.Watch.Stop()
+		g.RecordMove(MOVE_PAUSE, -1)
+		allSprites.TriggerEvent("Pause")
+	} else {
+		g.RecordMove(MOVE_UNPAUSE, -1)
+		g.TimerElapsed.Watch.Continue()
+		allSprites.TriggerEvent("Unpause")
+	}
+}
+
+// endMove checks for the end of the game after each move, and writes out the
+// replay once it's over.
+func (g *Grid) endMove() {
+	g.CheckGameOver()
+	if g.State == GAME_OVER {
+		g.SaveReplay()
+	}
+}
+
+// Neighbours returns the positions of the (up to) eight tiles around pos.
+func (g *Grid) Neighbours(pos int) []int {
+	var n []int
+	for _, p := range []int{
+		g.UpLeft(pos), g.Up(pos), g.UpRight(pos),
+		g.Left(pos), g.Right(pos),
+		g.DownLeft(pos), g.Down(pos), g.DownRight(pos),
+	} {
+		if p != -1 {
+			n = append(n, p)
+		}
+	}
+	return n
+}
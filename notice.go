@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+// Notice is a panel on the title screen for telling the player something
+// went wrong. Any key or click puts it away.
+type Notice struct {
+	sprite.BaseSprite
+	font    *sprite.Font
+	Message string
+}
+
+func NewNotice() *Notice {
+	n := &Notice{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	n.Init()
+
+	n.RegisterEvent("resizeScreen", func() {
+		if n.Visible {
+			n.draw()
+		}
+	})
+
+	return n
+}
+
+func (n *Notice) Open(msg string) {
+	n.Message = msg
+	n.draw()
+	n.Visible = true
+	allSprites.MoveToTop(n)
+}
+
+func (n *Notice) Close() {
+	n.Visible = false
+}
+
+func (n *Notice) draw() {
+	hint := "press any key"
+	lines := wrapText(n.Message, Width-2*SUMMARY_PAD-8)
+	w := len(hint) * 4
+	for _, l := range lines {
+		if len(l)*4 > w {
+			w = len(l) * 4
+		}
+	}
+	surf := panelSurface(w, (len(lines)+1)*LINE_HEIGHT+2)
+	for cnt, l := range lines {
+		surf.Blit(textSurface(n.font, l, 'r'), SUMMARY_PAD, SUMMARY_PAD+cnt*LINE_HEIGHT)
+	}
+	surf.Blit(textSurface(n.font, hint, 'G'), SUMMARY_PAD, SUMMARY_PAD+len(lines)*LINE_HEIGHT+2)
+
+	n.BlockCostumes = []*sprite.Surface{&surf}
+	n.SetCostume(0)
+	n.X = Width/2 - surf.Width/2
+	n.Y = Height/2 - surf.Height/2
+	if n.Y < 0 {
+		n.Y = 0
+	}
+}
+
+// wrapText breaks a message into lines no wider than width blocks, between
+// words where it can.
+func wrapText(s string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, w := range strings.Fields(s) {
+		if line != "" && len(line+" "+w)*4 > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+// The shades of the overlay, from certainly safe to certainly a mine.
+var overlayShades = []struct {
+	Below float64
+	Color rune
+}{
+	{0.001, 'b'},
+	{0.2, 'y'},
+	{0.5, 'o'},
+	{0.999, 'r'},
+	{2, 'd'},
+}
+
+// Overlay shades every covered tile by the chance of a mine being under it,
+// worked out from the numbers, the flags and the number of mines on the
+// board. Flags are taken as right, so the tiles under them aren't shaded.
+// It's for training, so it stays off in games which can make the high score
+// tables.
+type Overlay struct {
+	sprite.BaseSprite
+	On    bool
+	drawn string
+}
+
+func NewOverlay() *Overlay {
+	o := &Overlay{BaseSprite: sprite.BaseSprite{
+		X:       0,
+		Y:       HEADER_OFFSET,
+		Visible: false},
+	}
+	o.Init()
+	return o
+}
+
+// Ranked is whether a game can still make it onto the high score tables.
+func (g *Grid) Ranked() bool {
+	if g.Assisted || g.Code || g.Puzzle != "" || replayViewer != nil {
+		return false
+	}
+	for _, d := range difficulties {
+		if g.Difficulty == d {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleOverlay turns the overlay on or off. It can't be turned on in a
+// ranked game, but analysis mode marks the game as assisted so it can be used
+// there.
+func (g *Grid) ToggleOverlay() {
+	o := g.Overlay
+	if o.On && o.Visible {
+		o.On = false
+		return
+	}
+	if g.Ranked() {
+		g.HintBox.Say("the overlay is off in ranked games, press a first")
+		return
+	}
+	o.On = true
+}
+
+// Probabilities works out the chance of a mine for each tile, with flags
+// taken as mines.
+func (g *Grid) Probabilities() []float64 {
+	s := NewGridSolver(g)
+	for cnt, t := range g.Tiles {
+		if t.Covered && t.HaveFlag {
+			s.Known[cnt] = true
+		}
+	}
+	return s.Probabilities()
+}
+
+func overlayShade(p float64) rune {
+	for _, s := range overlayShades {
+		if p < s.Below {
+			return s.Color
+		}
+	}
+	return 'd'
+}
+
+// Update works the chances out again whenever a tile is opened or marked.
+func (o *Overlay) Update() {
+	g := gameGrid
+	if !o.On || g.Ranked() || g.Paused || g.State != GAME_RUNNING {
+		o.Visible = false
+		return
+	}
+	o.Visible = true
+
+	state := make([]byte, len(g.Tiles))
+	for cnt, t := range g.Tiles {
+		switch {
+		case !t.Covered:
+			state[cnt] = SAVE_REVEALED
+		case t.HaveFlag:
+			state[cnt] = SAVE_FLAG
+		case t.HaveQuestion:
+			state[cnt] = SAVE_QUESTION
+		default:
+			state[cnt] = SAVE_COVERED
+		}
+	}
+	if string(state) == o.drawn && len(o.BlockCostumes) > 0 {
+		return
+	}
+	o.drawn = string(state)
+
+	surf := sprite.NewSurface(g.Width*TILE_WIDTH, g.Height*TILE_HEIGHT, true)
+	for cnt, p := range g.Probabilities() {
+		if state[cnt] != SAVE_COVERED {
+			continue
+		}
+		c := overlayShade(p)
+		x0, y0 := (cnt%g.Width)*TILE_WIDTH, (cnt/g.Width)*TILE_HEIGHT
+		for y := y0 + 2; y < y0+TILE_HEIGHT-2; y++ {
+			for x := x0 + 2; x < x0+TILE_WIDTH-2; x++ {
+				surf.Blocks[y][x] = c
+			}
+		}
+	}
+	o.BlockCostumes = []*sprite.Surface{&surf}
+	o.SetCostume(0)
+}
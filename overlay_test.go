@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestRanked(t *testing.T) {
+	tests := []struct {
+		name   string
+		grid   Grid
+		ranked bool
+	}{
+		{"easy", Grid{Difficulty: "easy"}, true},
+		{"hard", Grid{Difficulty: "hard"}, true},
+		{"custom board", Grid{Difficulty: "custom"}, false},
+		{"assisted", Grid{Difficulty: "easy", Assisted: true}, false},
+		{"game code", Grid{Difficulty: "easy", Code: true}, false},
+		{"puzzle", Grid{Difficulty: PUZZLE_DIFFICULTY, Puzzle: "first steps"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.grid.Ranked(); got != tt.ranked {
+				t.Errorf("Ranked() = %v, want %v", got, tt.ranked)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+// Pointer tracks a mouse button which is held down on the board. Tiles under
+// the pointer are shown pressed in while the button is down, and are only
+// revealed when it's let go.
+type Pointer struct {
+	Down    bool
+	Chord   bool
+	Pos     int
+	Pressed []int
+}
+
+var pointer Pointer
+
+func (p *Pointer) Press(g *Grid, x, y int, chord bool) {
+	p.Down = true
+	p.Chord = chord
+	p.Move(g, x, y)
+	allSprites.TriggerEvent("PressTile")
+}
+
+// Move drags the pressed highlight to whichever tile is under the pointer.
+// Pressing on an uncovered number, or chording, presses in all of its
+// neighbours as well.
+func (p *Pointer) Move(g *Grid, x, y int) {
+	p.clear(g)
+	p.Pos = -1
+	if t := g.FindTileClicked(x, y); t != nil {
+		p.Pos = g.GetTilePos(t)
+	}
+	if p.Pos == -1 || g.Paused {
+		return
+	}
+
+	t := g.Tiles[p.Pos]
+	targets := []int{p.Pos}
+	if p.Chord || (!t.Covered && t.BombCount > 0) {
+		targets = append(targets, g.Neighbours(p.Pos)...)
+	}
+
+	for _, n := range targets {
+		t := g.Tiles[n]
+		if t.Covered && !t.HaveFlag && !t.HaveQuestion && t.RevealIn == 0 {
+			t.SetTile(TILE_COVERED_REVERSE)
+			p.Pressed = append(p.Pressed, n)
+		}
+	}
+}
+
+// Release lets go of the button at x, y. Letting go off the board cancels
+// the click.
+func (p *Pointer) Release(g *Grid, x, y int) {
+	if !p.Down {
+		return
+	}
+	p.Move(g, x, y)
+	p.clear(g)
+	p.Down = false
+	allSprites.TriggerEvent("ReleaseTile")
+
+	if p.Pos == -1 {
+		return
+	}
+	if p.Chord {
+		g.Chord(p.Pos)
+	} else {
+		g.Reveal(p.Pos)
+	}
+}
+
+func (p *Pointer) clear(g *Grid) {
+	for _, n := range p.Pressed {
+		if n < len(g.Tiles) {
+			g.Tiles[n].Redraw()
+		}
+	}
+	p.Pressed = nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"math"
+)
+
+// probBudget is how many steps counting the layouts can take before it gives
+// up and settles for an estimate instead.
+const probBudget = 200000
+
+// A component is a group of covered tiles next to numbers which only affect
+// each other, so their layouts can be counted on their own.
+type component struct {
+	tiles []int
+	cons  []constraint
+	// counts[k] is the number of layouts with k mines, and tileCounts[k][i]
+	// how many of those have a mine on tiles[i]
+	counts     []float64
+	tileCounts [][]float64
+}
+
+// lnChoose is the log of n choose k.
+func lnChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+func convolve(a, b []float64) []float64 {
+	out := make([]float64, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
+
+// components splits the covered tiles next to numbers into groups.
+func (s *Solver) components(cs []constraint) []*component {
+	index := map[int]int{}
+	var tiles []int
+	for _, c := range cs {
+		for _, t := range c.tiles {
+			if _, ok := index[t]; !ok {
+				index[t] = len(tiles)
+				tiles = append(tiles, t)
+			}
+		}
+	}
+
+	parent := make([]int, len(tiles))
+	for cnt := range parent {
+		parent[cnt] = cnt
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, c := range cs {
+		for _, t := range c.tiles[1:] {
+			parent[find(index[t])] = find(index[c.tiles[0]])
+		}
+	}
+
+	byRoot := map[int]*component{}
+	var comps []*component
+	get := func(root int) *component {
+		c, ok := byRoot[root]
+		if !ok {
+			c = &component{}
+			byRoot[root] = c
+			comps = append(comps, c)
+		}
+		return c
+	}
+	for cnt, t := range tiles {
+		c := get(find(cnt))
+		c.tiles = append(c.tiles, t)
+	}
+	for _, c := range cs {
+		comp := get(find(index[c.tiles[0]]))
+		comp.cons = append(comp.cons, c)
+	}
+	return comps
+}
+
+// count goes through every layout of mines in the component which fits its
+// numbers, using up the budget as it goes. It returns false if it ran out.
+func (c *component) count(maxMines int, budget *int) bool {
+	index := map[int]int{}
+	for cnt, t := range c.tiles {
+		index[t] = cnt
+	}
+	touching := make([][]int, len(c.tiles))
+	mines := make([]int, len(c.cons))
+	open := make([]int, len(c.cons))
+	for ci, con := range c.cons {
+		open[ci] = len(con.tiles)
+		for _, t := range con.tiles {
+			touching[index[t]] = append(touching[index[t]], ci)
+		}
+	}
+
+	c.counts = make([]float64, len(c.tiles)+1)
+	c.tileCounts = make([][]float64, len(c.tiles)+1)
+	for k := range c.tileCounts {
+		c.tileCounts[k] = make([]float64, len(c.tiles))
+	}
+	layout := make([]bool, len(c.tiles))
+
+	var walk func(i, placed int) bool
+	walk = func(i, placed int) bool {
+		*budget--
+		if *budget < 0 {
+			return false
+		}
+		if i == len(c.tiles) {
+			c.counts[placed]++
+			for t, m := range layout {
+				if m {
+					c.tileCounts[placed][t]++
+				}
+			}
+			return true
+		}
+
+		for _, mine := range []bool{false, true} {
+			if mine && placed == maxMines {
+				continue
+			}
+			ok := true
+			for _, ci := range touching[i] {
+				open[ci]--
+				if mine {
+					mines[ci]++
+				}
+				if mines[ci] > c.cons[ci].mines || mines[ci]+open[ci] < c.cons[ci].mines {
+					ok = false
+				}
+			}
+			layout[i] = mine
+			n := placed
+			if mine {
+				n++
+			}
+			if ok && !walk(i+1, n) {
+				return false
+			}
+			for _, ci := range touching[i] {
+				open[ci]++
+				if mine {
+					mines[ci]--
+				}
+			}
+		}
+		layout[i] = false
+		return true
+	}
+	return walk(0, 0)
+}
+
+// Probabilities works out the chance of a mine under each covered tile by
+// counting every layout of mines which fits the numbers and the number of
+// mines left. Open tiles come back as -1. If there are too many layouts to
+// count, it falls back to Estimate.
+func (s *Solver) Probabilities() []float64 {
+	p := make([]float64, len(s.Open))
+	left, unknown := s.Mines, 0
+	for pos := range s.Open {
+		switch {
+		case s.Open[pos]:
+			p[pos] = -1
+		case s.Known[pos]:
+			p[pos] = 1
+			left--
+		case s.Safe[pos]:
+			p[pos] = 0
+		default:
+			unknown++
+		}
+	}
+
+	cs := s.constraints()
+	comps := s.components(cs)
+	frontier := 0
+	budget := probBudget
+	for _, c := range comps {
+		frontier += len(c.tiles)
+		if !c.count(left, &budget) {
+			return s.Estimate()
+		}
+	}
+	interior := unknown - frontier
+
+	// weight[m] is how many ways the rest of the mines fit in the tiles away
+	// from the numbers, if m of them are next to numbers, scaled down to
+	// keep it in range
+	weight := make([]float64, frontier+1)
+	best := math.Inf(-1)
+	for m := range weight {
+		if r := left - m; r >= 0 && r <= interior {
+			if l := lnChoose(interior, r); l > best {
+				best = l
+			}
+		}
+	}
+	for m := range weight {
+		if r := left - m; r >= 0 && r <= interior {
+			weight[m] = math.Exp(lnChoose(interior, r) - best)
+		}
+	}
+
+	all := []float64{1}
+	for _, c := range comps {
+		all = convolve(all, c.counts)
+	}
+	var total, interiorMines float64
+	for m, n := range all {
+		total += n * weight[m]
+		if interior > 0 {
+			interiorMines += n * weight[m] * float64(left-m) / float64(interior)
+		}
+	}
+	if total == 0 || math.IsInf(total, 0) || math.IsNaN(total) {
+		return s.Estimate()
+	}
+
+	for ci, c := range comps {
+		others := []float64{1}
+		for oi, o := range comps {
+			if oi != ci {
+				others = convolve(others, o.counts)
+			}
+		}
+		for t, pos := range c.tiles {
+			var sum float64
+			for k, counts := range c.tileCounts {
+				if counts[t] == 0 {
+					continue
+				}
+				for j, n := range others {
+					if k+j < len(weight) {
+						sum += counts[t] * n * weight[k+j]
+					}
+				}
+			}
+			p[pos] = sum / total
+		}
+	}
+
+	inFrontier := map[int]bool{}
+	for _, c := range comps {
+		for _, t := range c.tiles {
+			inFrontier[t] = true
+		}
+	}
+	for pos := range p {
+		if !s.Open[pos] && !s.Known[pos] && !s.Safe[pos] && !inFrontier[pos] {
+			p[pos] = interiorMines / total
+		}
+	}
+	return p
+}
+
+// Estimate is a rough guess at the chance of a mine under each tile, from
+// the worst of the numbers around it, or the density of the rest of the
+// board for tiles which aren't next to a number. It copes with known mines
+// which don't fit the numbers, like a wrong flag.
+func (s *Solver) Estimate() []float64 {
+	p := make([]float64, len(s.Open))
+	left, unknown := s.Mines, 0
+	for pos := range s.Open {
+		switch {
+		case s.Open[pos]:
+			p[pos] = -1
+		case s.Known[pos]:
+			p[pos] = 1
+			left--
+		case s.Safe[pos]:
+			p[pos] = 0
+		default:
+			unknown++
+		}
+	}
+
+	rest := 0.0
+	if unknown > 0 && left > 0 {
+		rest = float64(left) / float64(unknown)
+	}
+	seen := map[int]bool{}
+	for _, c := range s.constraints() {
+		r := float64(c.mines) / float64(len(c.tiles))
+		if r < 0 {
+			r = 0
+		} else if r > 1 {
+			r = 1
+		}
+		for _, t := range c.tiles {
+			if !seen[t] || r > p[t] {
+				p[t] = r
+			}
+			seen[t] = true
+		}
+	}
+	for pos := range p {
+		if !s.Open[pos] && !s.Known[pos] && !s.Safe[pos] && !seen[pos] {
+			p[pos] = rest
+		}
+	}
+	return p
+}
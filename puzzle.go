@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const PUZZLE_DIFFICULTY = "puzzle"
+
+// A Puzzle is a board which starts partly open, and can be finished from
+// there without a single guess. Err is set if a puzzle file couldn't be read,
+// and Guess if it could but needs a guess somewhere.
+type Puzzle struct {
+	ID    string
+	Name  string
+	Board *Board
+	Guess bool
+	Err   error
+}
+
+// A PuzzleRecord is how you've got on with a puzzle so far.
+type PuzzleRecord struct {
+	Solved   bool  `json:"solved"`
+	Attempts int   `json:"attempts"`
+	BestMs   int64 `json:"best_ms,omitempty"`
+}
+
+// The bundled puzzles are made from their seeds by the generator, so they
+// come out the same every time.
+var puzzlePack = []struct {
+	Name   string
+	Width  int
+	Height int
+	Mines  int
+	Seed   int64
+}{
+	{"first steps", 8, 8, 8, 101},
+	{"corners", 8, 8, 10, 202},
+	{"small talk", 9, 9, 12, 303},
+	{"crossroads", 9, 9, 15, 404},
+	{"long hall", 16, 6, 16, 505},
+	{"checkers", 12, 8, 18, 606},
+	{"daily grind", 16, 8, 22, 707},
+	{"tight squeeze", 10, 10, 20, 808},
+	{"the field", 16, 10, 30, 909},
+	{"minefield", 16, 12, 38, 1010},
+	{"deep end", 16, 16, 50, 1111},
+	{"expert", 30, 16, 99, 1212},
+}
+
+// puzzles is everything on the list, loaded when it's first opened.
+var puzzles []*Puzzle
+
+// puzzleStart opens up tiles on a board until it can be finished without
+// guessing. It starts with an opening, and every time the solver gets stuck
+// it gives away one of the tiles the solver was stuck next to.
+func puzzleStart(w, h, total int, mines []bool, r *rand.Rand) []bool {
+	given := []int{}
+	zeros := []int{}
+	for pos := range mines {
+		if mines[pos] {
+			continue
+		}
+		count := 0
+		for _, n := range neighbours(w, h, pos) {
+			if mines[n] {
+				count++
+			}
+		}
+		if count == 0 {
+			zeros = append(zeros, pos)
+		}
+	}
+	if len(zeros) > 0 {
+		given = append(given, zeros[r.Intn(len(zeros))])
+	}
+
+	for {
+		s := NewSolver(w, h, total)
+		for _, pos := range given {
+			s.open(mines, pos)
+		}
+		start := append([]bool(nil), s.Open...)
+		if s.PlayOut(mines) {
+			return start
+		}
+
+		// prefer safe tiles next to the ones the solver opened
+		var near, rest []int
+		for pos := range mines {
+			if mines[pos] || s.Open[pos] {
+				continue
+			}
+			rest = append(rest, pos)
+			for _, n := range neighbours(w, h, pos) {
+				if s.Open[n] {
+					near = append(near, pos)
+					break
+				}
+			}
+		}
+		if len(near) > 0 {
+			rest = near
+		}
+		given = append(given, rest[r.Intn(len(rest))])
+	}
+}
+
+// GeneratePuzzle lays out mines from a seed and works out a start for them.
+func GeneratePuzzle(w, h, total int, seed int64) *Board {
+	b := &Board{Width: w, Height: h, Mines: placeMines(seed, w*h, total, -1)}
+	b.Cells = b.startCells(rand.New(rand.NewSource(seed)))
+	return b
+}
+
+func (b *Board) mineSet() []bool {
+	mines := make([]bool, b.Width*b.Height)
+	for _, m := range b.Mines {
+		mines[m] = true
+	}
+	return mines
+}
+
+func (b *Board) startCells(r *rand.Rand) []string {
+	open := puzzleStart(b.Width, b.Height, len(b.Mines), b.mineSet(), r)
+	var cells []string
+	for row := 0; row < b.Height; row++ {
+		line := make([]byte, b.Width)
+		for c := range line {
+			line[c] = SAVE_COVERED
+			if open[row*b.Width+c] {
+				line[c] = SAVE_REVEALED
+			}
+		}
+		cells = append(cells, string(line))
+	}
+	return cells
+}
+
+// solvable checks that a board can be finished from its open tiles without
+// guessing. Every step of the way is forced, so there's only one answer.
+func (b *Board) solvable() bool {
+	mines := b.mineSet()
+	s := NewSolver(b.Width, b.Height, len(b.Mines))
+	for row, line := range b.Cells {
+		for c, ch := range line {
+			if ch == SAVE_REVEALED {
+				s.open(mines, row*b.Width+c)
+			}
+		}
+	}
+	return s.PlayOut(mines)
+}
+
+func puzzleDir() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "puzzles"), nil
+}
+
+// loadPuzzles makes the bundled puzzles and reads any in the puzzles
+// directory. A board there without a layer of open tiles gets a start worked
+// out for it.
+func loadPuzzles() []*Puzzle {
+	ps := []*Puzzle{}
+	for cnt, p := range puzzlePack {
+		ps = append(ps, &Puzzle{
+			ID:    fmt.Sprintf("pack-%02d", cnt+1),
+			Name:  p.Name,
+			Board: GeneratePuzzle(p.Width, p.Height, p.Mines, p.Seed),
+		})
+	}
+
+	d, err := puzzleDir()
+	if err != nil {
+		return ps
+	}
+	files, err := ioutil.ReadDir(d)
+	if err != nil {
+		return ps
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	for _, fi := range files {
+		ext := strings.ToLower(filepath.Ext(fi.Name()))
+		if fi.IsDir() || (ext != ".txt" && ext != MBF_EXT) {
+			continue
+		}
+		name := strings.TrimSuffix(fi.Name(), filepath.Ext(fi.Name()))
+		p := &Puzzle{ID: "file:" + fi.Name(), Name: name}
+		p.Board, p.Err = LoadBoard(filepath.Join(d, fi.Name()))
+		if p.Err == nil {
+			if p.Board.Cells == nil {
+				h := fnv.New64a()
+				h.Write([]byte(fi.Name()))
+				p.Board.Cells = p.Board.startCells(rand.New(rand.NewSource(int64(h.Sum64()))))
+			}
+			p.Guess = !p.Board.solvable()
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+func findPuzzle(id string) *Puzzle {
+	if puzzles == nil {
+		puzzles = loadPuzzles()
+	}
+	for _, p := range puzzles {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// StartPuzzle lays out a puzzle with its open tiles already showing. The
+// current game is only thrown away once the puzzle is known to fit.
+func (g *Grid) StartPuzzle(p *Puzzle) error {
+	if p == nil {
+		return errors.New("the puzzle is no longer on the list")
+	} else if p.Err != nil {
+		return p.Err
+	}
+	b := p.Board
+	sg := &SaveGame{
+		Width:      b.Width,
+		Height:     b.Height,
+		TotalBombs: len(b.Mines),
+		Difficulty: PUZZLE_DIFFICULTY,
+		Puzzle:     p.ID,
+		Rules:      g.Rules,
+		Mines:      b.Mines,
+		Board:      b.Cells,
+	}
+	if err := sg.Validate(); err != nil {
+		return err
+	}
+	if !sg.Fits() {
+		return fmt.Errorf("the terminal is too small for a %dx%d board", sg.Width, sg.Height)
+	}
+	g.Reset()
+	if err := g.Restore(sg); err != nil {
+		return err
+	}
+	g.Record = settings.RecordReplays
+	return nil
+}
+
+// playPuzzle starts a puzzle picked from the title screen. If it can't be
+// laid out, because the puzzle file has changed or the terminal is too small
+// for it, the list stays open and says why.
+func (t *TitleOverlay) playPuzzle(p *Puzzle) {
+	if err := gameGrid.StartPuzzle(p); err != nil {
+		t.Puzzles.Open()
+		t.Notice.Open("couldn't start the puzzle, " + err.Error())
+		return
+	}
+	allSprites.TriggerEvent("SelectorClicked")
+}
+
+// minesFlagged is whether every mine has a flag on it. There can't be more
+// flags than mines, so none of them are wrong either.
+func (g *Grid) minesFlagged() bool {
+	for _, t := range g.Tiles {
+		if t.HaveBomb != t.HaveFlag {
+			return false
+		}
+	}
+	return true
+}
+
+func puzzleRecordsPath() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "puzzles.json"), nil
+}
+
+func readPuzzleRecords(fn string) (map[string]*PuzzleRecord, error) {
+	recs := map[string]*PuzzleRecord{}
+	data, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return recs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &recs); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return recs, nil
+}
+
+func loadPuzzleRecords() (map[string]*PuzzleRecord, error) {
+	fn, err := puzzleRecordsPath()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return readPuzzleRecords(fn)
+}
+
+// recordPuzzle counts an attempt at a puzzle, and keeps the best time of the
+// ones which solved it.
+func (g *Grid) recordPuzzle() {
+	r := g.Result
+	if r == nil || g.Puzzle == "" || replayViewer != nil {
+		return
+	}
+
+	id := g.Puzzle
+	solved := r.Won && !r.Assisted
+	ms := r.Elapsed.Milliseconds()
+	r.saveRecord(func() recordSave {
+		return recordSave{What: "puzzle", Err: savePuzzle(id, solved, ms)}
+	})
+}
+
+// savePuzzle adds an attempt at the puzzle id to the puzzle records.
+func savePuzzle(id string, solved bool, ms int64) error {
+	fn, err := puzzleRecordsPath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	recs, err := readPuzzleRecords(fn)
+	if err != nil {
+		return err
+	}
+	rec, ok := recs[id]
+	if !ok {
+		rec = &PuzzleRecord{}
+		recs[id] = rec
+	}
+	rec.Attempts++
+	if solved {
+		if !rec.Solved || ms < rec.BestMs {
+			rec.BestMs = ms
+		}
+		rec.Solved = true
+	}
+
+	data, err := json.MarshalIndent(recs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fn, data)
+}
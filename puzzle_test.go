@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSavePuzzle(t *testing.T) {
+	d := tempDir(t)
+	defer os.RemoveAll(d)
+	old := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", d)
+	defer os.Setenv("XDG_DATA_HOME", old)
+
+	attempts := []struct {
+		solved bool
+		ms     int64
+	}{{false, 9000}, {true, 30000}, {true, 20000}, {true, 25000}}
+	for _, a := range attempts {
+		if err := savePuzzle("corners", a.solved, a.ms); err != nil {
+			t.Fatalf("savePuzzle() = %v", err)
+		}
+	}
+
+	recs, err := loadPuzzleRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := recs["corners"]
+	if rec == nil || rec.Attempts != 4 || !rec.Solved || rec.BestMs != 20000 {
+		t.Errorf("record = %+v, want 4 attempts solved in 20000ms", rec)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+
+	sprite "github.com/pdevine/go-asciisprite"
+	tm "github.com/pdevine/go-asciisprite/termbox"
+)
+
+// PuzzleList shows every puzzle over the title screen, with whether it's been
+// solved and the best time. The arrow keys pick one and Enter plays it.
+type PuzzleList struct {
+	sprite.BaseSprite
+	font     *sprite.Font
+	Records  map[string]*PuzzleRecord
+	Selected int
+	Message  string
+	first    int
+	rowsY    int
+	shown    int
+}
+
+func NewPuzzleList() *PuzzleList {
+	l := &PuzzleList{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	l.Init()
+
+	l.RegisterEvent("resizeScreen", func() {
+		if l.Visible {
+			l.draw()
+		}
+	})
+
+	return l
+}
+
+func (l *PuzzleList) Open() {
+	if puzzles == nil {
+		puzzles = loadPuzzles()
+	}
+	l.Records, _ = loadPuzzleRecords()
+	l.Message = ""
+	l.draw()
+	l.Visible = true
+	allSprites.MoveToTop(l)
+}
+
+func (l *PuzzleList) Close() {
+	l.Visible = false
+}
+
+// pick checks that the selected puzzle can be played, and returns it if so.
+func (l *PuzzleList) pick() *Puzzle {
+	p := puzzles[l.Selected]
+	switch {
+	case p.Err != nil:
+		l.Message = "this puzzle file can't be read"
+	case !p.Board.Fits():
+		l.Message = "too big for this terminal"
+	default:
+		l.Close()
+		return p
+	}
+	l.draw()
+	return nil
+}
+
+// HandleKey moves through the list, and returns the puzzle to play once
+// Enter is pressed.
+func (l *PuzzleList) HandleKey(ev tm.Event) *Puzzle {
+	switch ev.Key {
+	case tm.KeyArrowUp:
+		l.Selected = (l.Selected + len(puzzles) - 1) % len(puzzles)
+	case tm.KeyArrowDown:
+		l.Selected = (l.Selected + 1) % len(puzzles)
+	case tm.KeyEnter:
+		return l.pick()
+	case tm.KeyEsc:
+		l.Close()
+		return nil
+	}
+	l.Message = ""
+	l.draw()
+	return nil
+}
+
+// Click plays whichever puzzle is under the mouse.
+func (l *PuzzleList) Click(x, y int) *Puzzle {
+	row := (y - l.Y - l.rowsY) / LINE_HEIGHT
+	if y < l.Y+l.rowsY || row >= l.shown {
+		return nil
+	}
+	l.Selected = l.first + row
+	return l.pick()
+}
+
+func (l *PuzzleList) status(p *Puzzle) string {
+	if p.Err != nil {
+		return "bad file"
+	}
+	rec, ok := l.Records[p.ID]
+	switch {
+	case ok && rec.Solved:
+		return "solved " + msText(rec.BestMs)
+	case p.Guess:
+		return "guess"
+	case ok:
+		return "tried"
+	}
+	return "new"
+}
+
+func (l *PuzzleList) row(cnt int, p *Puzzle) string {
+	size := "?"
+	if p.Board != nil {
+		size = fmt.Sprintf("%dx%d", p.Board.Width, p.Board.Height)
+	}
+	return fmt.Sprintf("%2d %-12.12s %5s %s", cnt+1, p.Name, size, l.status(p))
+}
+
+// draw shows as much of the list as fits on the screen, scrolling to keep the
+// selected puzzle in view.
+func (l *PuzzleList) draw() {
+	title := "puzzles"
+	hint := "enter to play, esc to close"
+	if l.Message != "" {
+		hint = l.Message
+	}
+
+	l.shown = (Height-2*SUMMARY_PAD-2*LINE_HEIGHT-4)/LINE_HEIGHT - 1
+	if l.shown > len(puzzles) {
+		l.shown = len(puzzles)
+	} else if l.shown < 1 {
+		l.shown = 1
+	}
+	if l.Selected < l.first {
+		l.first = l.Selected
+	} else if l.Selected >= l.first+l.shown {
+		l.first = l.Selected - l.shown + 1
+	}
+
+	rows := []string{}
+	for cnt := l.first; cnt < l.first+l.shown; cnt++ {
+		rows = append(rows, l.row(cnt, puzzles[cnt]))
+	}
+
+	w := len(hint) * 4
+	for _, r := range append(rows, title) {
+		if len(r)*4 > w {
+			w = len(r) * 4
+		}
+	}
+	h := LINE_HEIGHT + 2 + len(rows)*LINE_HEIGHT + 2 + LINE_HEIGHT
+	surf := panelSurface(w, h)
+
+	t := textSurface(l.font, title, 'b')
+	surf.Blit(t, surf.Width/2-t.Width/2, SUMMARY_PAD)
+	l.rowsY = SUMMARY_PAD + LINE_HEIGHT + 2
+	for cnt, r := range rows {
+		c := 'X'
+		p := puzzles[l.first+cnt]
+		if l.first+cnt == l.Selected {
+			c = 'b'
+		} else if p.Err != nil || !p.Board.Fits() {
+			c = 'G'
+		}
+		surf.Blit(textSurface(l.font, r, c), SUMMARY_PAD, l.rowsY+cnt*LINE_HEIGHT)
+	}
+	hc := 'G'
+	if l.Message != "" {
+		hc = 'r'
+	}
+	surf.Blit(textSurface(l.font, hint, hc), SUMMARY_PAD, l.rowsY+len(rows)*LINE_HEIGHT+2)
+
+	l.BlockCostumes = []*sprite.Surface{&surf}
+	l.SetCostume(0)
+	l.X = Width/2 - surf.Width/2
+	l.Y = Height/2 - surf.Height/2
+	if l.Y < 0 {
+		l.Y = 0
+	}
+}
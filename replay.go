@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// replayVersion is the version of the replay file format described in
+// docs/replay-format.md. Bump it whenever a change would break a reader.
+const replayVersion = 1
+
+const (
+	REPLAY_HEADER = "header"
+	REPLAY_MOVE   = "move"
+	REPLAY_RESULT = "result"
+)
+
+type ReplayHeader struct {
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	TotalBombs int       `json:"total_bombs"`
+	Mines      [][2]int  `json:"mines"`
+	Board      []string  `json:"board,omitempty"`
+	Seed       int64     `json:"seed"`
+	Difficulty string    `json:"difficulty"`
+	Rules      Rules     `json:"rules"`
+	Assisted   bool      `json:"assisted"`
+	Recorded   time.Time `json:"recorded"`
+}
+
+type ReplayMove struct {
+	Type string   `json:"type"`
+	Kind MoveKind `json:"kind"`
+	X    *int     `json:"x,omitempty"`
+	Y    *int     `json:"y,omitempty"`
+	T    int64    `json:"t"`
+}
+
+type ReplayResult struct {
+	Type    string `json:"type"`
+	Won     bool   `json:"won"`
+	Elapsed int64  `json:"elapsed"`
+}
+
+// A Replay is everything needed to play a game back: the layout of the board
+// and every move made on it.
+type Replay struct {
+	Header ReplayHeader
+	Moves  []Move
+	Result *ReplayResult
+}
+
+func NewReplay(g *Grid) *Replay {
+	r := &Replay{
+		Header: ReplayHeader{
+			Type:       REPLAY_HEADER,
+			Version:    replayVersion,
+			Width:      g.Width,
+			Height:     g.Height,
+			TotalBombs: g.TotalBombs,
+			Mines:      [][2]int{},
+			Board:      g.StartCells,
+			Seed:       g.Seed,
+			Difficulty: g.Difficulty,
+			Rules:      g.Rules,
+			Assisted:   g.Assisted,
+			Recorded:   gameClock.Now().UTC(),
+		},
+		Moves: g.Moves,
+	}
+
+	for cnt, t := range g.Tiles {
+		if t.HaveBomb {
+			r.Header.Mines = append(r.Header.Mines, [2]int{cnt % g.Width, cnt / g.Width})
+		}
+	}
+
+	if g.Result != nil {
+		r.Result = &ReplayResult{
+			Type:    REPLAY_RESULT,
+			Won:     g.Result.Won,
+			Elapsed: g.Result.Elapsed.Milliseconds(),
+		}
+	}
+	return r
+}
+
+// Write saves the replay as JSON lines: a header, one line per move, and a
+// result line if the game was finished.
+func (r *Replay) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(r.Header); err != nil {
+		return err
+	}
+
+	for _, m := range r.Moves {
+		rm := ReplayMove{
+			Type: REPLAY_MOVE,
+			Kind: m.Kind,
+			T:    m.T.Milliseconds(),
+		}
+		if m.Pos >= 0 {
+			x, y := m.Pos%r.Header.Width, m.Pos/r.Header.Width
+			rm.X = &x
+			rm.Y = &y
+		}
+		if err := enc.Encode(rm); err != nil {
+			return err
+		}
+	}
+
+	if r.Result != nil {
+		return enc.Encode(r.Result)
+	}
+	return nil
+}
+
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	r := &Replay{}
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var kind struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(line, &kind); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+
+		if lineNo == 1 && kind.Type != REPLAY_HEADER {
+			return nil, errors.New("line 1: replay doesn't start with a header")
+		}
+
+		switch kind.Type {
+		case REPLAY_HEADER:
+			if lineNo != 1 {
+				return nil, fmt.Errorf("line %d: unexpected header", lineNo)
+			}
+			if err := json.Unmarshal(line, &r.Header); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			if r.Header.Version <= 0 {
+				return nil, fmt.Errorf("line %d: invalid replay version %d", lineNo, r.Header.Version)
+			}
+			if r.Header.Version > replayVersion {
+				return nil, fmt.Errorf("line %d: replay version %d is newer than this version of bombitron", lineNo, r.Header.Version)
+			}
+			if r.Header.Width <= 0 || r.Header.Height <= 0 {
+				return nil, fmt.Errorf("line %d: invalid board size %dx%d", lineNo, r.Header.Width, r.Header.Height)
+			}
+			seen := map[[2]int]bool{}
+			for _, m := range r.Header.Mines {
+				if !r.onBoard(m[0], m[1]) {
+					return nil, fmt.Errorf("line %d: mine at %d,%d is off the board", lineNo, m[0], m[1])
+				}
+				if seen[m] {
+					return nil, fmt.Errorf("line %d: there's already a mine at %d,%d", lineNo, m[0], m[1])
+				}
+				seen[m] = true
+			}
+			if len(r.Header.Mines) != r.Header.TotalBombs {
+				return nil, fmt.Errorf("line %d: header says %d mines but lists %d", lineNo, r.Header.TotalBombs, len(r.Header.Mines))
+			}
+			if r.Header.Board != nil {
+				if err := r.Header.SaveGame().Validate(); err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNo, err)
+				}
+			}
+		case REPLAY_MOVE:
+			var rm ReplayMove
+			if err := json.Unmarshal(line, &rm); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			m := Move{Kind: rm.Kind, Pos: -1, T: time.Duration(rm.T) * time.Millisecond}
+			if rm.X != nil && rm.Y != nil {
+				if !r.onBoard(*rm.X, *rm.Y) {
+					return nil, fmt.Errorf("line %d: move at %d,%d is off the board", lineNo, *rm.X, *rm.Y)
+				}
+				m.Pos = *rm.Y*r.Header.Width + *rm.X
+			}
+			r.Moves = append(r.Moves, m)
+		case REPLAY_RESULT:
+			r.Result = &ReplayResult{}
+			if err := json.Unmarshal(line, r.Result); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+		default:
+			// skip lines we don't know about so newer minor additions
+			// to the format can still be read
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNo == 0 {
+		return nil, errors.New("replay is empty")
+	}
+	return r, nil
+}
+
+// SaveGame is the board as it stood before the first move, for replays of
+// games which didn't start fully covered such as puzzles.
+func (h ReplayHeader) SaveGame() *SaveGame {
+	sg := &SaveGame{
+		Width:      h.Width,
+		Height:     h.Height,
+		TotalBombs: h.TotalBombs,
+		Difficulty: h.Difficulty,
+		Seed:       h.Seed,
+		Rules:      h.Rules,
+		Board:      h.Board,
+	}
+	for _, m := range h.Mines {
+		sg.Mines = append(sg.Mines, m[1]*h.Width+m[0])
+	}
+	return sg
+}
+
+func (r *Replay) onBoard(x, y int) bool {
+	return x >= 0 && y >= 0 && x < r.Header.Width && y < r.Header.Height
+}
+
+func replayDir() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	d = filepath.Join(d, "replays")
+	if err := os.MkdirAll(d, 0755); err != nil {
+		return "", err
+	}
+	return d, nil
+}
+
+// SaveReplay writes the game to the replay directory if it's being recorded.
+// Replays are rewritten in place, so the file can be written more than once.
+func (g *Grid) SaveReplay() (string, error) {
+	if !g.Record || len(g.Moves) == 0 {
+		return "", nil
+	}
+
+	d, err := replayDir()
+	if err != nil {
+		return "", err
+	}
+
+	// games in analysis mode can end more than once, so keep writing over
+	// the same file with the latest moves
+	r := NewReplay(g)
+	var f *os.File
+	if g.ReplayFile == "" {
+		f, err = createNewFile(d, r.Header.Recorded.Format("20060102-150405.000")+"-"+g.Difficulty, ".jsonl")
+		if err != nil {
+			return "", err
+		}
+		g.ReplayFile = f.Name()
+	} else {
+		f, err = os.Create(g.ReplayFile)
+		if err != nil {
+			return "", err
+		}
+	}
+	fn := g.ReplayFile
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := r.Write(w); err != nil {
+		return "", err
+	}
+	return fn, w.Flush()
+}
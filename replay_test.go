@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayRoundTrip(t *testing.T) {
+	r := &Replay{
+		Header: ReplayHeader{
+			Type:       REPLAY_HEADER,
+			Version:    replayVersion,
+			Width:      4,
+			Height:     3,
+			TotalBombs: 2,
+			Mines:      [][2]int{{0, 0}, {3, 2}},
+			Board:      []string{"#...", "....", "###F"},
+			Seed:       42,
+			Difficulty: "custom",
+			Recorded:   testEpoch,
+		},
+		Moves: []Move{
+			{Kind: MOVE_REVEAL, Pos: 5, T: 0},
+			{Kind: MOVE_FLAG, Pos: 0, T: 1200 * time.Millisecond},
+			{Kind: MOVE_PAUSE, Pos: -1, T: 2 * time.Second},
+		},
+		Result: &ReplayResult{Type: REPLAY_RESULT, Won: true, Elapsed: 3000},
+	}
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Width != 4 || got.Header.Height != 3 || len(got.Header.Mines) != 2 || len(got.Header.Board) != 3 {
+		t.Errorf("header = %+v", got.Header)
+	}
+	if len(got.Moves) != len(r.Moves) {
+		t.Fatalf("read %d moves, want %d", len(got.Moves), len(r.Moves))
+	}
+	for cnt, m := range r.Moves {
+		if got.Moves[cnt] != m {
+			t.Errorf("move %d = %+v, want %+v", cnt, got.Moves[cnt], m)
+		}
+	}
+	if got.Result == nil || !got.Result.Won || got.Result.Elapsed != 3000 {
+		t.Errorf("result = %+v", got.Result)
+	}
+}
+
+func TestReadReplay(t *testing.T) {
+	header := `{"type":"header","version":1,"width":3,"height":2,"total_bombs":1,"mines":[[2,1]]}`
+
+	tests := []struct {
+		name  string
+		lines []string
+		moves int
+		want  string
+	}{
+		{"header only", []string{header}, 0, ""},
+		{"moves and result", []string{
+			header,
+			`{"type":"move","kind":"reveal","x":0,"y":0,"t":0}`,
+			`{"type":"move","kind":"pause","t":10}`,
+			`{"type":"result","won":false,"elapsed":20}`,
+		}, 2, ""},
+		{"unknown lines are skipped", []string{header, `{"type":"comment","text":"hi"}`}, 0, ""},
+		{"blank lines are skipped", []string{header, "", `{"type":"move","kind":"reveal","x":1,"y":1,"t":0}`}, 1, ""},
+		{"empty", []string{}, 0, "empty"},
+		{"no header", []string{`{"type":"move","kind":"reveal","x":0,"y":0,"t":0}`}, 0, "header"},
+		{"second header", []string{header, header}, 0, "unexpected header"},
+		{"newer version", []string{`{"type":"header","version":99,"width":3,"height":2}`}, 0, "newer"},
+		{"bad size", []string{`{"type":"header","version":1,"width":0,"height":2}`}, 0, "board size"},
+		{"no version", []string{`{"type":"header","width":3,"height":2,"total_bombs":1,"mines":[[2,1]]}`}, 0, "invalid replay version"},
+		{"mine off the board", []string{`{"type":"header","version":1,"width":3,"height":2,"total_bombs":1,"mines":[[3,0]]}`}, 0, "off the board"},
+		{"same mine twice", []string{`{"type":"header","version":1,"width":3,"height":2,"total_bombs":2,"mines":[[2,1],[2,1]]}`}, 0, "already a mine"},
+		{"wrong mine count", []string{`{"type":"header","version":1,"width":3,"height":2,"total_bombs":2,"mines":[[2,1]]}`}, 0, "line 1: header says 2 mines"},
+		{"starting board", []string{`{"type":"header","version":1,"width":3,"height":2,"total_bombs":1,"mines":[[2,1]],"board":["..#","F##"]}`}, 0, ""},
+		{"starting board open on a mine", []string{`{"type":"header","version":1,"width":3,"height":2,"total_bombs":1,"mines":[[2,1]],"board":["###","..."]}`}, 0, "line 1: tile 3,2 is open on a mine"},
+		{"starting board too short", []string{`{"type":"header","version":1,"width":3,"height":2,"total_bombs":1,"mines":[[2,1]],"board":["###"]}`}, 0, "line 1: board has 1 rows"},
+		{"move off the board", []string{header, `{"type":"move","kind":"reveal","x":0,"y":2,"t":0}`}, 0, "line 2"},
+		{"bad json", []string{header, `{"type":`}, 0, "line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ReadReplay(strings.NewReader(strings.Join(tt.lines, "\n")))
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("ReadReplay() = %v, want an error containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadReplay() = %v", err)
+			}
+			if len(r.Moves) != tt.moves {
+				t.Errorf("read %d moves, want %d", len(r.Moves), tt.moves)
+			}
+		})
+	}
+}
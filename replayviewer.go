@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	sprite "github.com/pdevine/go-asciisprite"
+	tm "github.com/pdevine/go-asciisprite/termbox"
+)
+
+var replaySpeeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// ReplayViewer plays a recorded game back through the normal board. The game
+// reads its time from a ManualClock which the viewer moves along with the
+// playback position.
+type ReplayViewer struct {
+	Replay   *Replay
+	Clock    *ManualClock
+	Bar      *ReplayBar
+	Cursor   *ReplayCursor
+	Applied  int
+	Position time.Duration
+	Speed    int
+	Playing  bool
+	Guesses  []Guess
+	Guess    int
+	base     time.Time
+	lastTick time.Time
+}
+
+type ReplayBar struct {
+	sprite.BaseSprite
+	font   *sprite.Font
+	viewer *ReplayViewer
+}
+
+type ReplayCursor struct {
+	sprite.BaseSprite
+}
+
+var replayViewer *ReplayViewer
+
+func NewReplayViewer(r *Replay) *ReplayViewer {
+	v := &ReplayViewer{
+		Replay: r,
+		Speed:  2,
+		base:   r.Header.Recorded,
+	}
+	v.Clock = NewManualClock(v.base)
+	return v
+}
+
+func loadReplayViewer(fn string) (*ReplayViewer, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := ReadReplay(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return NewReplayViewer(r), nil
+}
+
+// Start lays out the replay's board once the grid is ready, as long as it
+// fits in the terminal.
+func (v *ReplayViewer) Start() error {
+	h := v.Replay.Header
+	if Width/TILE_WIDTH < h.Width || (Height-HEADER_OFFSET)/TILE_HEIGHT < h.Height {
+		return fmt.Errorf("the terminal is too small for a %dx%d board", h.Width, h.Height)
+	}
+
+	v.Bar = NewReplayBar(v)
+	v.Cursor = NewReplayCursor()
+	allSprites.Sprites = append(allSprites.Sprites, v.Bar)
+	allSprites.Sprites = append(allSprites.Sprites, v.Cursor)
+
+	gameGrid.Width = v.Replay.Header.Width
+	gameGrid.Height = v.Replay.Header.Height
+	gameGrid.Difficulty = v.Replay.Header.Difficulty
+	gameGrid.Seed = v.Replay.Header.Seed
+	gameGrid.Rules = v.Replay.Header.Rules
+	if err := v.rewind(); err != nil {
+		return err
+	}
+	v.findGuesses()
+	allSprites.TriggerEvent("resizeScreen")
+
+	v.Playing = true
+	v.lastTick = time.Now()
+	return nil
+}
+
+// Duration is the length of the replay.
+func (v *ReplayViewer) Duration() time.Duration {
+	if v.Replay.Result != nil {
+		return time.Duration(v.Replay.Result.Elapsed) * time.Millisecond
+	}
+	if len(v.Replay.Moves) > 0 {
+		return v.Replay.Moves[len(v.Replay.Moves)-1].T
+	}
+	return 0
+}
+
+// rewind puts the board back the way it was before the first move. Once
+// Start has done this successfully it can't fail later on, since the board
+// doesn't change.
+func (v *ReplayViewer) rewind() error {
+	v.Clock.Set(v.base)
+	gameGrid.Reset()
+
+	if v.Replay.Header.Board != nil {
+		if err := gameGrid.Restore(v.Replay.Header.SaveGame()); err != nil {
+			return err
+		}
+		// a replay being watched isn't recorded again
+		gameGrid.Record = false
+	} else {
+		gameGrid.State = GAME_STARTED
+		gameGrid.LayMines(v.Replay.Header.SaveGame().Mines)
+	}
+	gameGrid.Analysis = true
+
+	v.Applied = 0
+	v.Position = 0
+	v.Cursor.Visible = false
+	return nil
+}
+
+// applyNext plays the next move of the replay on the board.
+func (v *ReplayViewer) applyNext() {
+	if v.Applied >= len(v.Replay.Moves) {
+		return
+	}
+
+	m := v.Replay.Moves[v.Applied]
+	v.Clock.Set(v.base.Add(m.T))
+	gameGrid.ApplyMove(m)
+	v.Applied++
+
+	if m.Pos >= 0 {
+		v.Cursor.MoveTo(gameGrid.Tiles[m.Pos])
+	}
+}
+
+// findGuesses plays the whole replay through once to pick out the guesses,
+// then goes back to the start.
+func (v *ReplayViewer) findGuesses() {
+	v.Guesses = nil
+	v.Guess = -1
+	for v.Applied < len(v.Replay.Moves) {
+		n := len(gameGrid.Guesses)
+		v.applyNext()
+		if len(gameGrid.Guesses) > n {
+			gs := gameGrid.Guesses[n]
+			gs.Move = v.Applied - 1
+			v.Guesses = append(v.Guesses, gs)
+		}
+	}
+	v.rewind()
+}
+
+// jumpGuess goes to just before the next or previous guess, and points out
+// the tile which was safe.
+func (v *ReplayViewer) jumpGuess(dir int) {
+	if len(v.Guesses) == 0 {
+		return
+	}
+	v.Playing = false
+	if v.Guess < 0 && dir < 0 {
+		v.Guess = len(v.Guesses)
+	}
+	v.Guess = (v.Guess + dir + len(v.Guesses)) % len(v.Guesses)
+	gs := v.Guesses[v.Guess]
+	v.seek(gs.Move)
+	v.Cursor.MoveTo(gameGrid.Tiles[gs.Pos])
+	gameGrid.showGuess(gs)
+}
+
+// seek moves playback to just after the nth move.
+func (v *ReplayViewer) seek(n int) {
+	if n < 0 {
+		n = 0
+	} else if n > len(v.Replay.Moves) {
+		n = len(v.Replay.Moves)
+	}
+
+	if n < v.Applied {
+		v.rewind()
+	}
+	for v.Applied < n {
+		v.applyNext()
+	}
+
+	if n == 0 {
+		v.Position = 0
+	} else if n == len(v.Replay.Moves) {
+		v.Position = v.Duration()
+	} else {
+		v.Position = v.Replay.Moves[n-1].T
+	}
+	v.Clock.Set(v.base.Add(v.Position))
+}
+
+// Tick moves playback along by however much real time has passed.
+func (v *ReplayViewer) Tick() {
+	now := time.Now()
+	dt := now.Sub(v.lastTick)
+	v.lastTick = now
+
+	if !v.Playing || gameGrid.State == GAME_READY {
+		return
+	}
+
+	v.Position += time.Duration(float64(dt) * replaySpeeds[v.Speed])
+	for v.Applied < len(v.Replay.Moves) && v.Replay.Moves[v.Applied].T <= v.Position {
+		v.applyNext()
+	}
+
+	if v.Position >= v.Duration() && v.Applied == len(v.Replay.Moves) {
+		v.Position = v.Duration()
+		v.Playing = false
+	}
+	if gameGrid.State == GAME_RUNNING && !gameGrid.Paused {
+		v.Clock.Set(v.base.Add(v.Position))
+	}
+}
+
+func (v *ReplayViewer) HandleKey(ev tm.Event) {
+	switch {
+	case ev.Ch == 'p' || ev.Key == tm.KeySpace:
+		if !v.Playing && v.Applied == len(v.Replay.Moves) {
+			v.seek(0)
+		}
+		v.Playing = !v.Playing
+	case ev.Ch == '+' || ev.Ch == '=' || ev.Key == tm.KeyArrowUp:
+		if v.Speed < len(replaySpeeds)-1 {
+			v.Speed++
+		}
+	case ev.Ch == '-' || ev.Key == tm.KeyArrowDown:
+		if v.Speed > 0 {
+			v.Speed--
+		}
+	case ev.Key == tm.KeyArrowRight || ev.Ch == '.':
+		v.Playing = false
+		v.seek(v.Applied + 1)
+	case ev.Key == tm.KeyArrowLeft || ev.Ch == ',':
+		v.Playing = false
+		v.seek(v.Applied - 1)
+	case ev.Key == tm.KeyHome:
+		v.Playing = false
+		v.seek(0)
+	case ev.Key == tm.KeyEnd:
+		v.Playing = false
+		v.seek(len(v.Replay.Moves))
+	case ev.Ch == 'g':
+		v.jumpGuess(1)
+	case ev.Ch == 'G':
+		v.jumpGuess(-1)
+	case ev.Ch == 'o':
+		gameGrid.ToggleOverlay()
+	}
+	v.lastTick = time.Now()
+}
+
+// ApplyMove carries out a recorded move on the board.
+func (g *Grid) ApplyMove(m Move) {
+	switch m.Kind {
+	case MOVE_REVEAL:
+		g.Reveal(m.Pos)
+	case MOVE_FLAG, MOVE_QUESTION, MOVE_UNMARK:
+		// right clicks cycle through the markers, so keep going until the
+		// tile ends up the way it was recorded
+		for cnt := 0; cnt < 3 && markKind(g.Tiles[m.Pos]) != m.Kind; cnt++ {
+			g.ToggleMark(m.Pos)
+		}
+	case MOVE_CHORD:
+		g.Chord(m.Pos)
+	case MOVE_PAUSE:
+		if !g.Paused {
+			g.TogglePause()
+		}
+	case MOVE_UNPAUSE:
+		if g.Paused {
+			g.TogglePause()
+		}
+	case MOVE_UNDO:
+		g.Undo()
+	case MOVE_REDO:
+		g.Redo()
+	case MOVE_HINT:
+		g.Hints++
+	}
+}
+
+func NewReplayBar(v *ReplayViewer) *ReplayBar {
+	b := &ReplayBar{BaseSprite: sprite.BaseSprite{
+		Visible: true},
+		font:   sprite.NewPakuFont(),
+		viewer: v,
+	}
+	b.Init()
+	return b
+}
+
+// Update redraws the timeline between the flag count and the timer, with
+// the playback speed and move count underneath it.
+func (b *ReplayBar) Update() {
+	x0 := 24
+	x1 := Width - 44
+	if x1-x0 < 10 {
+		b.Visible = false
+		return
+	}
+	b.Visible = true
+	b.X = x0
+	b.Y = 1
+
+	v := b.viewer
+	surf := sprite.NewSurface(x1-x0, HUD_Y-2, true)
+	filled := surf.Width
+	if d := v.Duration(); d > 0 {
+		filled = int(float64(surf.Width) * float64(v.Position) / float64(d))
+	}
+	for x := 0; x < surf.Width; x++ {
+		c := 'G'
+		if x < filled {
+			c = 'b'
+		}
+		surf.Blocks[0][x] = c
+		surf.Blocks[1][x] = c
+	}
+
+	state := "play"
+	if !v.Playing {
+		state = "pause"
+	}
+	s := fmt.Sprintf("%gx %d/%d %s", replaySpeeds[v.Speed], v.Applied, len(v.Replay.Moves), state)
+	if v.Guess >= 0 {
+		s += fmt.Sprintf(" guess %d/%d", v.Guess+1, len(v.Guesses))
+	} else if len(v.Guesses) > 0 {
+		s += fmt.Sprintf(" guesses %d", len(v.Guesses))
+	}
+	txt := sprite.NewSurfaceFromString(b.font.BuildString(s), true)
+	surf.Blit(txt, 0, 3)
+
+	b.BlockCostumes = []*sprite.Surface{&surf}
+}
+
+// NewReplayCursor draws a box around the tile of the last move played.
+func NewReplayCursor() *ReplayCursor {
+	c := &ReplayCursor{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+	}
+	c.Init()
+
+	surf := sprite.NewSurface(TILE_WIDTH, TILE_HEIGHT, true)
+	surf.Rectangle(0, 0, TILE_WIDTH-1, TILE_HEIGHT-1, 'r')
+	c.BlockCostumes = []*sprite.Surface{&surf}
+	return c
+}
+
+func (c *ReplayCursor) MoveTo(t *Tile) {
+	c.X = t.GridX
+	c.Y = t.GridY
+	c.Visible = true
+	allSprites.MoveToTop(c)
+}
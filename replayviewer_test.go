@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestReplayViewerStartBoard(t *testing.T) {
+	Width, Height = 200, 100
+	// the events the board sends are only for the sprites on screen, so
+	// they're soaked up without being run
+	allSprites.Events = make(chan string)
+	go func() {
+		for range allSprites.Events {
+		}
+	}()
+	gameGrid = NewGrid()
+	gameGrid.SetReady()
+
+	// a puzzle which starts with the left column open, and one reveal
+	r := &Replay{
+		Header: ReplayHeader{
+			Type:       REPLAY_HEADER,
+			Version:    replayVersion,
+			Width:      4,
+			Height:     3,
+			TotalBombs: 2,
+			Mines:      [][2]int{{3, 0}, {3, 2}},
+			Board:      []string{".###", ".###", ".##F"},
+			Difficulty: PUZZLE_DIFFICULTY,
+			Recorded:   testEpoch,
+		},
+		Moves: []Move{{Kind: MOVE_REVEAL, Pos: 1}},
+	}
+	v := NewReplayViewer(r)
+	if err := v.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	g := gameGrid
+	if g.State != GAME_RUNNING {
+		t.Errorf("state = %v, want GAME_RUNNING", g.State)
+	}
+	for cnt, tl := range g.Tiles {
+		mine := cnt == 3 || cnt == 11
+		if tl.HaveBomb != mine {
+			t.Errorf("tile %d mine = %v, want %v", cnt, tl.HaveBomb, mine)
+		}
+		open := cnt%4 == 0
+		if tl.Covered == open {
+			t.Errorf("tile %d covered = %v, want %v", cnt, tl.Covered, !open)
+		}
+	}
+	if !g.Tiles[11].HaveFlag {
+		t.Errorf("flag at 3,2 wasn't put back")
+	}
+
+	v.seek(1)
+	if g.Tiles[1].Covered {
+		t.Errorf("tile 1 is still covered after the reveal")
+	}
+	v.seek(0)
+	if !g.Tiles[1].Covered || g.Tiles[0].Covered {
+		t.Errorf("rewinding didn't put the starting board back")
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// saveVersion is bumped whenever the layout of SaveGame changes. Older saves
+// are upgraded in ReadSaveGame so they can still be resumed.
+const saveVersion = 1
+
+const saveFileName = "savegame.json"
+
+// Each cell of a saved board is one of these characters.
+const (
+	SAVE_COVERED  = '#'
+	SAVE_REVEALED = '.'
+	SAVE_FLAG     = 'F'
+	SAVE_QUESTION = '?'
+)
+
+type SaveGame struct {
+	Version    int       `json:"version"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	TotalBombs int       `json:"total_bombs"`
+	Difficulty string    `json:"difficulty"`
+	Daily      string    `json:"daily,omitempty"`
+	Puzzle     string    `json:"puzzle,omitempty"`
+	Code       bool      `json:"code,omitempty"`
+	Seed       int64     `json:"seed"`
+	Rules      Rules     `json:"rules"`
+	Assisted   bool      `json:"assisted,omitempty"`
+	Hints      int       `json:"hints,omitempty"`
+	ElapsedMs  int64     `json:"elapsed_ms"`
+	Mines      []int     `json:"mines"`
+	Start      *int      `json:"start,omitempty"`
+	Board      []string  `json:"board"`
+	SavedAt    time.Time `json:"saved_at"`
+}
+
+// dataDir returns the directory where bombitron keeps its files, following the
+// XDG base directory spec.
+func dataDir() (string, error) {
+	d := os.Getenv("XDG_DATA_HOME")
+	if d == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		d = filepath.Join(home, ".local", "share")
+	}
+	d = filepath.Join(d, "bombitron")
+	if err := os.MkdirAll(d, 0755); err != nil {
+		return "", err
+	}
+	return d, nil
+}
+
+func savePath() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, saveFileName), nil
+}
+
+// writeFileAtomic writes to a temporary file first so that a crash part way
+// through can't leave a truncated file behind.
+func writeFileAtomic(fn string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), filepath.Base(fn)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), fn)
+}
+
+// createNewFile makes a new file in d without writing over another one,
+// adding a counter to the name if two are made at the same moment.
+func createNewFile(d, name, ext string) (*os.File, error) {
+	for cnt := 0; ; cnt++ {
+		fn := filepath.Join(d, name+ext)
+		if cnt > 0 {
+			fn = filepath.Join(d, fmt.Sprintf("%s-%d%s", name, cnt, ext))
+		}
+		f, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil || !os.IsExist(err) || cnt >= 100 {
+			return f, err
+		}
+	}
+}
+
+func NewSaveGame(g *Grid) *SaveGame {
+	sg := &SaveGame{
+		Version:    saveVersion,
+		Width:      g.Width,
+		Height:     g.Height,
+		TotalBombs: g.TotalBombs,
+		Difficulty: g.Difficulty,
+		Daily:      g.Daily,
+		Puzzle:     g.Puzzle,
+		Code:       g.Code,
+		Seed:       g.Seed,
+		Rules:      g.Rules,
+		Assisted:   g.Assisted,
+		Hints:      g.Hints,
+		ElapsedMs:  g.TimerElapsed.Watch.Elapsed().Milliseconds(),
+		Mines:      []int{},
+		SavedAt:    gameClock.Now().UTC(),
+	}
+	if g.Start >= 0 {
+		start := g.Start
+		sg.Start = &start
+	}
+
+	for r := 0; r < g.Height; r++ {
+		row := make([]byte, g.Width)
+		for c := 0; c < g.Width; c++ {
+			pos := r*g.Width + c
+			t := g.Tiles[pos]
+			if t.HaveBomb {
+				sg.Mines = append(sg.Mines, pos)
+			}
+			switch {
+			case !t.Covered:
+				row[c] = SAVE_REVEALED
+			case t.HaveFlag:
+				row[c] = SAVE_FLAG
+			case t.HaveQuestion:
+				row[c] = SAVE_QUESTION
+			default:
+				row[c] = SAVE_COVERED
+			}
+		}
+		sg.Board = append(sg.Board, string(row))
+	}
+	return sg
+}
+
+// Fits checks that the board will fit in the terminal.
+func (sg *SaveGame) Fits() bool {
+	return Width/TILE_WIDTH >= sg.Width && (Height-HEADER_OFFSET)/TILE_HEIGHT >= sg.Height
+}
+
+// Validate checks that a save makes sense before anything is laid out from
+// it.
+func (sg *SaveGame) Validate() error {
+	if sg.Width <= 0 || sg.Height <= 0 {
+		return fmt.Errorf("invalid board size %dx%d", sg.Width, sg.Height)
+	}
+	if len(sg.Board) != sg.Height {
+		return fmt.Errorf("board has %d rows, expected %d", len(sg.Board), sg.Height)
+	}
+	for cnt, row := range sg.Board {
+		if len(row) != sg.Width {
+			return fmt.Errorf("board row %d has %d cells, expected %d", cnt+1, len(row), sg.Width)
+		}
+	}
+	if len(sg.Mines) != sg.TotalBombs {
+		return fmt.Errorf("save has %d mines, expected %d", len(sg.Mines), sg.TotalBombs)
+	}
+	mines := map[int]bool{}
+	for _, m := range sg.Mines {
+		if m < 0 || m >= sg.Width*sg.Height {
+			return fmt.Errorf("mine at %d is off the board", m)
+		}
+		if mines[m] {
+			return fmt.Errorf("mine at %d is there twice", m)
+		}
+		mines[m] = true
+	}
+	flags := 0
+	for r, row := range sg.Board {
+		for c, ch := range row {
+			switch ch {
+			case SAVE_REVEALED:
+				if mines[r*sg.Width+c] {
+					return fmt.Errorf("tile %d,%d is open on a mine", c+1, r+1)
+				}
+			case SAVE_FLAG:
+				flags++
+			case SAVE_COVERED, SAVE_QUESTION:
+			default:
+				return fmt.Errorf("board row %d has an unknown cell %q", r+1, ch)
+			}
+		}
+	}
+	if flags > sg.TotalBombs {
+		return fmt.Errorf("save has %d flags but only %d mines", flags, sg.TotalBombs)
+	}
+	return nil
+}
+
+func WriteSaveGame(fn string, sg *SaveGame) error {
+	data, err := json.MarshalIndent(sg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fn, data)
+}
+
+func ReadSaveGame(fn string) (*SaveGame, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	sg := &SaveGame{}
+	if err := json.Unmarshal(data, sg); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case sg.Version <= 0:
+		return nil, errors.New("save game is missing a version")
+	case sg.Version > saveVersion:
+		return nil, fmt.Errorf("save game version %d is newer than this version of bombitron", sg.Version)
+	}
+	// upgrades from older versions go here, e.g.
+	//   if sg.Version < 2 { ...; sg.Version = 2 }
+
+	if err := sg.Validate(); err != nil {
+		return nil, err
+	}
+	return sg, nil
+}
+
+func haveSaveGame() bool {
+	fn, err := savePath()
+	if err != nil {
+		return false
+	}
+	_, err = ReadSaveGame(fn)
+	return err == nil
+}
+
+// saveGame writes out a game which is still in progress. Games which have
+// finished have nothing to resume, so any old save is removed instead.
+func saveGame(g *Grid) error {
+	if g.State == GAME_OVER {
+		removeSaveGame()
+		return nil
+	} else if g.State != GAME_RUNNING {
+		return nil
+	}
+	fn, err := savePath()
+	if err != nil {
+		return err
+	}
+	return WriteSaveGame(fn, NewSaveGame(g))
+}
+
+func removeSaveGame() {
+	fn, err := savePath()
+	if err != nil {
+		return
+	}
+	os.Remove(fn)
+}
+
+// Restore lays out the board from a save game and carries on where it left off.
+func (g *Grid) Restore(sg *SaveGame) error {
+	if g.State != GAME_READY {
+		return errors.New("can't restore a game while another one is in progress")
+	}
+	if err := sg.Validate(); err != nil {
+		return err
+	}
+	if !sg.Fits() {
+		return fmt.Errorf("the terminal is too small for a %dx%d board", sg.Width, sg.Height)
+	}
+
+	g.SetSize(sg.Width, sg.Height)
+	g.TotalBombs = sg.TotalBombs
+	g.Difficulty = sg.Difficulty
+	g.Daily = sg.Daily
+	g.Puzzle = sg.Puzzle
+	g.Code = sg.Code
+	if sg.Start != nil {
+		g.Start = *sg.Start
+	}
+	g.Seed = sg.Seed
+	g.Rules = sg.Rules
+	g.Assisted = sg.Assisted
+	g.Hints = sg.Hints
+	g.StartCells = sg.Board
+	g.Record = settings.RecordReplays
+
+	for _, m := range sg.Mines {
+		g.Tiles[m].HaveBomb = true
+	}
+	for cnt := range g.Tiles {
+		g.FindSurroundingBombs(cnt)
+	}
+
+	flags := 0
+	for r, row := range sg.Board {
+		for c, ch := range row {
+			t := g.Tiles[r*g.Width+c]
+			switch ch {
+			case SAVE_REVEALED:
+				t.Covered = false
+				t.SetTile(TileType(t.BombCount))
+			case SAVE_FLAG:
+				t.HaveFlag = true
+				t.SetTile(TILE_FLAG)
+				flags++
+			case SAVE_QUESTION:
+				t.HaveQuestion = true
+				t.SetTile(TILE_QUESTION)
+			}
+		}
+	}
+
+	g.FlagsRemaining.Remaining = g.TotalBombs - flags
+	g.State = GAME_RUNNING
+	g.TimerElapsed.Watch.Resume(time.Duration(sg.ElapsedMs) * time.Millisecond)
+
+	allSprites.TriggerEvent("resizeScreen")
+	allSprites.TriggerEvent("ShowFlagsRemaining")
+	allSprites.TriggerEvent("StartTimer")
+	return nil
+}
+
+// resumeGame carries on with the saved game. A save which can't be read is
+// removed, but one which is only too big for the terminal is kept for later.
+func resumeGame(g *Grid) error {
+	fn, err := savePath()
+	if err != nil {
+		return err
+	}
+	sg, err := ReadSaveGame(fn)
+	if err != nil {
+		removeSaveGame()
+		return err
+	}
+	return g.Restore(sg)
+}
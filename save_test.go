@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSaveGameValidate(t *testing.T) {
+	good := func() *SaveGame {
+		return &SaveGame{
+			Width:      3,
+			Height:     2,
+			TotalBombs: 2,
+			Mines:      []int{0, 5},
+			Board:      []string{"F#.", "?.#"},
+		}
+	}
+
+	tests := []struct {
+		name string
+		edit func(sg *SaveGame)
+		want string
+	}{
+		{"good", func(sg *SaveGame) {}, ""},
+		{"no size", func(sg *SaveGame) { sg.Width = 0 }, "invalid board size"},
+		{"missing row", func(sg *SaveGame) { sg.Board = sg.Board[:1] }, "rows"},
+		{"short row", func(sg *SaveGame) { sg.Board[1] = "?." }, "cells"},
+		{"wrong mine count", func(sg *SaveGame) { sg.Mines = []int{0} }, "expected 2"},
+		{"mine off the board", func(sg *SaveGame) { sg.Mines[1] = 6 }, "off the board"},
+		{"negative mine", func(sg *SaveGame) { sg.Mines[0] = -1 }, "off the board"},
+		{"duplicate mine", func(sg *SaveGame) { sg.Mines[1] = 0 }, "twice"},
+		{"open on a mine", func(sg *SaveGame) { sg.Board[1] = "?.." }, "open on a mine"},
+		{"as many flags as mines", func(sg *SaveGame) { sg.Board[0] = "FF." }, ""},
+		{"more flags than mines", func(sg *SaveGame) { sg.Board = []string{"FF.", "?.F"} }, "flags"},
+		{"unknown cell", func(sg *SaveGame) { sg.Board[0] = "F*." }, "unknown cell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sg := good()
+			tt.edit(sg)
+			err := sg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateNewFile(t *testing.T) {
+	d, err := ioutil.TempDir("", "bombitron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	seen := map[string]bool{}
+	for cnt := 0; cnt < 3; cnt++ {
+		f, err := createNewFile(d, "20260102-030405.000-expert", ".jsonl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if seen[f.Name()] {
+			t.Errorf("%s was handed out twice", f.Name())
+		}
+		seen[f.Name()] = true
+	}
+}
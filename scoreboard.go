@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	sprite "github.com/pdevine/go-asciisprite"
+	tm "github.com/pdevine/go-asciisprite/termbox"
+)
+
+var difficulties = []string{"easy", "med.", "hard"}
+
+// ScoreBoard shows the high score table for one difficulty and size of board
+// at a time over the title screen. The arrow keys flip between the tables,
+// and h between games won with and without hints.
+type ScoreBoard struct {
+	sprite.BaseSprite
+	font       *sprite.Font
+	Scores     []Score
+	Difficulty int
+	Category   int
+	Hinted     bool
+}
+
+func NewScoreBoard() *ScoreBoard {
+	b := &ScoreBoard{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	b.Init()
+
+	b.RegisterEvent("resizeScreen", func() {
+		if b.Visible {
+			b.draw()
+		}
+	})
+
+	return b
+}
+
+// Open reads the scores and shows the table for the size of board the
+// terminal currently fits.
+func (b *ScoreBoard) Open() {
+	b.Scores, _ = loadScores()
+	c := sizeCategory(Width/TILE_WIDTH, (Height-HEADER_OFFSET)/TILE_HEIGHT)
+	for cnt, n := range sizeCategories {
+		if n == c {
+			b.Category = cnt
+		}
+	}
+	b.draw()
+	b.Visible = true
+	allSprites.MoveToTop(b)
+}
+
+func (b *ScoreBoard) Close() {
+	b.Visible = false
+}
+
+func (b *ScoreBoard) HandleKey(ev tm.Event) {
+	switch ev.Key {
+	case tm.KeyArrowLeft:
+		b.Difficulty = (b.Difficulty + len(difficulties) - 1) % len(difficulties)
+	case tm.KeyArrowRight:
+		b.Difficulty = (b.Difficulty + 1) % len(difficulties)
+	case tm.KeyArrowUp:
+		b.Category = (b.Category + len(sizeCategories) - 1) % len(sizeCategories)
+	case tm.KeyArrowDown:
+		b.Category = (b.Category + 1) % len(sizeCategories)
+	default:
+		if ev.Ch == 'h' {
+			b.Hinted = !b.Hinted
+			break
+		}
+		b.Close()
+		return
+	}
+	b.draw()
+}
+
+// scoreRows formats a table as lines of text, leaving off columns from the
+// right until it's no wider than width blocks.
+func scoreRows(scores []Score, width int) []string {
+	for cols := 5; cols > 2; cols-- {
+		rows := []string{}
+		fits := true
+		for cnt, s := range scores {
+			parts := []string{
+				fmt.Sprintf("%2d", cnt+1),
+				fmt.Sprintf("%8s", formatElapsed(s.Elapsed(), 2)),
+				fmt.Sprintf("%-8.8s", s.Player),
+				fmt.Sprintf("%5dx%-3d", s.Width, s.Height),
+				s.Date.Local().Format("2006-01-02"),
+			}
+			r := strings.TrimRight(strings.Join(parts[:cols], " "), " ")
+			if len(r)*4 > width {
+				fits = false
+			}
+			rows = append(rows, r)
+		}
+		if fits {
+			return rows
+		}
+	}
+
+	rows := []string{}
+	for cnt, s := range scores {
+		rows = append(rows, fmt.Sprintf("%2d %s", cnt+1, formatElapsed(s.Elapsed(), 2)))
+	}
+	return rows
+}
+
+func (b *ScoreBoard) draw() {
+	d := difficulties[b.Difficulty]
+	c := sizeCategories[b.Category]
+	title := fmt.Sprintf("%s %s boards", d, c)
+	if b.Hinted {
+		title += " hinted"
+	}
+
+	rows := scoreRows(HighScores(b.Scores, d, c, b.Hinted), Width-2*SUMMARY_PAD-8)
+	if len(rows) == 0 {
+		rows = []string{"no wins yet"}
+	}
+
+	w := len(title) * 4
+	for _, r := range rows {
+		if len(r)*4 > w {
+			w = len(r) * 4
+		}
+	}
+
+	h := LINE_HEIGHT + 2 + len(rows)*LINE_HEIGHT
+	surf := panelSurface(w, h)
+
+	t := textSurface(b.font, title, 'b')
+	surf.Blit(t, surf.Width/2-t.Width/2, SUMMARY_PAD)
+	for cnt, r := range rows {
+		surf.Blit(textSurface(b.font, r, 'X'), SUMMARY_PAD, SUMMARY_PAD+LINE_HEIGHT+2+cnt*LINE_HEIGHT)
+	}
+
+	b.BlockCostumes = []*sprite.Surface{&surf}
+	b.SetCostume(0)
+	b.X = Width/2 - surf.Width/2
+	b.Y = Height/2 - surf.Height/2
+	if b.Y < 0 {
+		b.Y = 0
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	highScoreCount = 10
+	lockTimeout    = 5 * time.Second
+	lockStale      = 30 * time.Second
+)
+
+// A Score is one finished game. Every game is kept, won or lost, and the
+// high score tables are worked out from them when they're needed. Puzzles
+// and games started from a code are tagged, since their boards are known
+// ahead of time.
+type Score struct {
+	Won        bool      `json:"won"`
+	ElapsedMs  int64     `json:"elapsed_ms"`
+	BBBV       int       `json:"3bv"`
+	Efficiency int       `json:"efficiency"`
+	Seed       int64     `json:"seed"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	Mines      int       `json:"mines"`
+	Difficulty string    `json:"difficulty"`
+	Daily      string    `json:"daily,omitempty"`
+	Puzzle     string    `json:"puzzle,omitempty"`
+	Code       bool      `json:"code,omitempty"`
+	Rules      Rules     `json:"rules"`
+	Assisted   bool      `json:"assisted,omitempty"`
+	Hints      int       `json:"hints,omitempty"`
+	LossCause  string    `json:"loss_cause,omitempty"`
+	Date       time.Time `json:"date"`
+	Player     string    `json:"player"`
+}
+
+var sizeCategories = []string{"small", "medium", "large"}
+
+// sizeCategory groups boards by how many tiles they have, since the size of
+// the board depends on the size of the terminal.
+func sizeCategory(w, h int) string {
+	switch n := w * h; {
+	case n < 200:
+		return "small"
+	case n < 600:
+		return "medium"
+	}
+	return "large"
+}
+
+func (s Score) Elapsed() time.Duration {
+	return time.Duration(s.ElapsedMs) * time.Millisecond
+}
+
+func (s Score) Category() string {
+	return sizeCategory(s.Width, s.Height)
+}
+
+// Ranked is whether the game can be in the high score tables.
+func (s Score) Ranked() bool {
+	return !s.Assisted && !s.Code && s.Puzzle == ""
+}
+
+func NewScore(g *Grid) Score {
+	total, _ := g.BoardValue()
+	return Score{
+		Won:        g.Result.Won,
+		ElapsedMs:  g.Result.Elapsed.Milliseconds(),
+		BBBV:       total,
+		Efficiency: g.Efficiency(),
+		Seed:       g.Seed,
+		Width:      g.Width,
+		Height:     g.Height,
+		Mines:      g.TotalBombs,
+		Difficulty: g.Difficulty,
+		Daily:      g.Daily,
+		Puzzle:     g.Puzzle,
+		Code:       g.Code,
+		Rules:      g.Rules,
+		Assisted:   g.Result.Assisted,
+		Hints:      g.Result.Hints,
+		LossCause:  lossCause(g),
+		Date:       g.Result.FinishedAt,
+		Player:     settings.Player,
+	}
+}
+
+func scoresPath() (string, error) {
+	d, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "scores.jsonl"), nil
+}
+
+// lockFile takes a lock on fn by creating a lock file next to it, which works
+// the same everywhere. A lock which has been held for too long is assumed to
+// have been left behind by a crash and gets broken.
+func lockFile(fn string) (func(), error) {
+	lock := fn + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(lock); err == nil && time.Since(fi.ModTime()) > lockStale {
+			breakLock(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", lock)
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// breakLock gets rid of a stale lock. It's renamed out of the way first so
+// that only one process can break it, and if another process has broken it
+// and taken a fresh lock in the meantime, that one is put back.
+func breakLock(lock string) {
+	moved := fmt.Sprintf("%s.%d-%d", lock, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lock, moved); err != nil {
+		return
+	}
+	if fi, err := os.Stat(moved); err == nil && time.Since(fi.ModTime()) <= lockStale {
+		os.Link(moved, lock)
+	}
+	os.Remove(moved)
+}
+
+// AppendScore adds a game to the end of the scores file.
+func AppendScore(fn string, s Score) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadScores reads every game in the scores file. Lines which can't be read
+// are skipped so that one bad line doesn't lose the whole history.
+func ReadScores(fn string) ([]Score, error) {
+	unlock, err := lockFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	f, err := os.Open(fn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readScores(f)
+}
+
+func readScores(r io.Reader) ([]Score, error) {
+	scores := []Score{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var s Score
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			continue
+		}
+		scores = append(scores, s)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func loadScores() ([]Score, error) {
+	fn, err := scoresPath()
+	if err != nil {
+		return nil, err
+	}
+	return ReadScores(fn)
+}
+
+// HighScores picks out the fastest ranked wins for a difficulty and size
+// category. Games won with hints have a table of their own.
+func HighScores(scores []Score, difficulty, category string, hinted bool) []Score {
+	best := []Score{}
+	for _, s := range scores {
+		if s.Won && s.Ranked() && (s.Hints > 0) == hinted && s.Difficulty == difficulty && s.Category() == category {
+			best = append(best, s)
+		}
+	}
+	sort.SliceStable(best, func(i, j int) bool {
+		return best[i].ElapsedMs < best[j].ElapsedMs
+	})
+	if len(best) > highScoreCount {
+		best = best[:highScoreCount]
+	}
+	return best
+}
+
+// bestTime finds the fastest ranked win without hints on boards exactly like
+// the one in game.
+func bestTime(scores []Score, game Score) time.Duration {
+	var best time.Duration
+	for _, s := range scores {
+		if !s.Won || !s.Ranked() || s.Hints > 0 || s.Difficulty != game.Difficulty || s.Width != game.Width || s.Height != game.Height {
+			continue
+		}
+		if best == 0 || s.Elapsed() < best {
+			best = s.Elapsed()
+		}
+	}
+	return best
+}
+
+// recordWrites counts the records of finished games still being written, so
+// that bombitron can wait for them before it exits.
+var recordWrites sync.WaitGroup
+
+// A recordSave is how writing one of the records of a finished game went.
+// Best and NewBest are only set for the scores file.
+type recordSave struct {
+	What    string
+	Best    time.Duration
+	NewBest bool
+	Err     error
+}
+
+// saveRecord writes a record of the game away from the game loop, since
+// another copy of bombitron may be holding the lock on the file. The summary
+// picks up how it went when it's ready.
+func (r *Result) saveRecord(save func() recordSave) {
+	done := make(chan recordSave, 1)
+	r.saving = append(r.saving, done)
+	recordWrites.Add(1)
+	go func() {
+		defer recordWrites.Done()
+		done <- save()
+	}()
+}
+
+// recordScore adds the game which just finished to the scores file, and
+// checks a win against the personal best for the board.
+func (g *Grid) recordScore() {
+	r := g.Result
+	if r == nil || replayViewer != nil {
+		return
+	}
+
+	s := NewScore(g)
+	key := ""
+	if r.Won && g.Ranked() && r.Hints == 0 {
+		key = bestsKey(g)
+	}
+	r.saveRecord(func() recordSave {
+		return saveScore(s, key)
+	})
+}
+
+// saveScore appends a game to the scores file. If bestsKey is set, the game
+// counts towards the personal bests under that key.
+func saveScore(s Score, bestsKey string) recordSave {
+	res := recordSave{What: "score"}
+	fn, err := scoresPath()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	scores, err := ReadScores(fn)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	if bestsKey != "" {
+		res.Best, res.NewBest = recordBest(bestsKey, s.Elapsed(), bestTime(scores, s))
+	}
+	res.Err = AppendScore(fn, s)
+	return res
+}
+
+// checkSaved picks up how saving the records went as each one finishes, and
+// says whether any have only just come in.
+func (r *Result) checkSaved() bool {
+	changed := false
+	pending := r.saving[:0]
+	for _, c := range r.saving {
+		select {
+		case res := <-c:
+			changed = true
+			if res.What == "score" {
+				r.Best = res.Best
+				r.NewBest = res.NewBest
+			}
+			if res.Err != nil {
+				r.Unsaved = append(r.Unsaved, res.What)
+			}
+		default:
+			pending = append(pending, c)
+		}
+	}
+	r.saving = pending
+	return changed
+}
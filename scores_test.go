@@ -0,0 +1,196 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	d, err := ioutil.TempDir("", "bombitron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestLockFile(t *testing.T) {
+	d := tempDir(t)
+	defer os.RemoveAll(d)
+	fn := filepath.Join(d, "scores.jsonl")
+	lock := fn + ".lock"
+	old := time.Now().Add(-2 * lockStale)
+
+	tests := []struct {
+		name  string
+		setup func()
+	}{
+		{"no lock", func() {}},
+		{"stale lock", func() {
+			ioutil.WriteFile(lock, []byte("1\n"), 0644)
+			os.Chtimes(lock, old, old)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			unlock, err := lockFile(fn)
+			if err != nil {
+				t.Fatalf("lockFile() = %v", err)
+			}
+			if _, err := os.Stat(lock); err != nil {
+				t.Errorf("lock file missing while held: %v", err)
+			}
+			unlock()
+			if _, err := os.Stat(lock); !os.IsNotExist(err) {
+				t.Errorf("lock file left behind after unlock")
+			}
+		})
+	}
+}
+
+func TestBreakLock(t *testing.T) {
+	d := tempDir(t)
+	defer os.RemoveAll(d)
+	lock := filepath.Join(d, "scores.jsonl.lock")
+	old := time.Now().Add(-2 * lockStale)
+
+	tests := []struct {
+		name  string
+		mtime time.Time
+		kept  bool
+	}{
+		{"stale lock is broken", old, false},
+		{"fresh lock is put back", time.Now(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ioutil.WriteFile(lock, []byte("1\n"), 0644)
+			os.Chtimes(lock, tt.mtime, tt.mtime)
+			breakLock(lock)
+			_, err := os.Stat(lock)
+			if kept := err == nil; kept != tt.kept {
+				t.Errorf("lock kept = %v, want %v", kept, tt.kept)
+			}
+			files, _ := ioutil.ReadDir(d)
+			if len(files) > 1 {
+				t.Errorf("%d files left behind", len(files))
+			}
+			os.Remove(lock)
+		})
+	}
+}
+
+func TestLockFileExcludes(t *testing.T) {
+	d := tempDir(t)
+	defer os.RemoveAll(d)
+	fn := filepath.Join(d, "scores.jsonl")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	held := 0
+	for cnt := 0; cnt < 8; cnt++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := lockFile(fn)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			held++
+			if held > 1 {
+				t.Error("lock held twice at once")
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			held--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHighScores(t *testing.T) {
+	win := func(ms int64) Score {
+		return Score{Won: true, ElapsedMs: ms, Width: 10, Height: 10, Difficulty: "easy"}
+	}
+	scores := []Score{win(3000), win(1000), win(2000)}
+
+	lost := win(500)
+	lost.Won = false
+	assisted := win(400)
+	assisted.Assisted = true
+	code := win(300)
+	code.Code = true
+	puzzle := win(200)
+	puzzle.Puzzle = "p1"
+	hinted := win(100)
+	hinted.Hints = 1
+	other := win(50)
+	other.Difficulty = "hard"
+	big := win(60)
+	big.Width = 40
+	scores = append(scores, lost, assisted, code, puzzle, hinted, other, big)
+
+	tests := []struct {
+		name       string
+		difficulty string
+		category   string
+		hinted     bool
+		want       []int64
+	}{
+		{"ranked wins in order", "easy", "small", false, []int64{1000, 2000, 3000}},
+		{"hinted table", "easy", "small", true, []int64{100}},
+		{"other difficulty", "hard", "small", false, []int64{50}},
+		{"other size", "easy", "medium", false, []int64{60}},
+		{"empty", "med.", "small", false, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HighScores(scores, tt.difficulty, tt.category, tt.hinted)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d scores, want %d", len(got), len(tt.want))
+			}
+			for cnt, s := range got {
+				if s.ElapsedMs != tt.want[cnt] {
+					t.Errorf("score %d = %d, want %d", cnt, s.ElapsedMs, tt.want[cnt])
+				}
+			}
+		})
+	}
+}
+
+func TestSaveScore(t *testing.T) {
+	d := tempDir(t)
+	defer os.RemoveAll(d)
+	old := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", d)
+	defer os.Setenv("XDG_DATA_HOME", old)
+
+	game := Score{Won: true, ElapsedMs: 20000, Difficulty: "easy", Width: 10, Height: 8}
+	if res := saveScore(game, "easy 10x8"); res.Err != nil || !res.NewBest || res.Best != 0 {
+		t.Errorf("first win = %+v, want a new best", res)
+	}
+	game.ElapsedMs = 30000
+	if res := saveScore(game, "easy 10x8"); res.Err != nil || res.NewBest || res.Best != 20*time.Second {
+		t.Errorf("slower win = %+v, want a best of 20s", res)
+	}
+
+	// a scores file which can't be read means the game isn't saved
+	fn := filepath.Join(d, "bombitron", "scores.jsonl")
+	os.Remove(fn)
+	os.Mkdir(fn, 0755)
+	if res := saveScore(game, ""); res.Err == nil {
+		t.Errorf("saveScore() = %+v, want an error", res)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Settings come from the config file, then the environment, and then the
+// command line, with each one overriding the last.
+type Settings struct {
+	TimerPrecision  int      `json:"timer_precision"`
+	RecordReplays   bool     `json:"record_replays"`
+	ReducedMotion   bool     `json:"reduced_motion"`
+	HoverNeighbours bool     `json:"hover_neighbours"`
+	HoverGuides     bool     `json:"hover_guides"`
+	HUD             []string `json:"hud"`
+	Player          string   `json:"player"`
+	Clipboard       bool     `json:"clipboard"`
+	Card            string   `json:"card"`
+	CardStyle       string   `json:"card_style"`
+	Training        bool     `json:"training"`
+}
+
+var settings = Settings{
+	HUD:       hudOrder,
+	CardStyle: "emoji",
+}
+
+func configPath() (string, error) {
+	d := os.Getenv("XDG_CONFIG_HOME")
+	if d == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		d = filepath.Join(home, ".config")
+	}
+	return filepath.Join(d, "bombitron", "config.json"), nil
+}
+
+// loadConfig reads the config file if there is one. Not having one is fine.
+func loadConfig() error {
+	fn, err := configPath()
+	if err != nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	return nil
+}
+
+func envBool(name string, v *bool) {
+	s, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	*v = s != "" && s != "0" && s != "false"
+}
+
+func parseFlags() {
+	if err := loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring config file: %v\n", err)
+	}
+	envBool("BOMBITRON_REDUCED_MOTION", &settings.ReducedMotion)
+
+	flag.IntVar(&settings.TimerPrecision, "precision", settings.TimerPrecision, "number of decimal places shown on the game timer (0-3)")
+	flag.BoolVar(&settings.RecordReplays, "record", settings.RecordReplays, "record a replay of every game")
+	flag.BoolVar(&settings.ReducedMotion, "reduced-motion", settings.ReducedMotion, "turn off sliding, bouncing, particles and other animations")
+	flag.BoolVar(&settings.HoverNeighbours, "hover-neighbours", settings.HoverNeighbours, "outline the tiles around the one under the pointer")
+	flag.BoolVar(&settings.HoverGuides, "hover-guides", settings.HoverGuides, "draw guides along the row and column under the pointer")
+	flag.StringVar(&settings.Player, "player", settings.Player, "name to put on the high score table")
+	flag.BoolVar(&settings.Clipboard, "clipboard", settings.Clipboard, "copy the game code to the clipboard at the end of every game")
+	flag.StringVar(&settings.Card, "card", settings.Card, "write a result card for the last game to this file when quitting, or - for stdout")
+	flag.StringVar(&settings.CardStyle, "card-style", settings.CardStyle, "draw the result card with emoji or text")
+	flag.BoolVar(&settings.Training, "training", settings.Training, "point out reveals which were guesses while a safe tile could be proved")
+	hud := flag.String("hud", strings.Join(settings.HUD, ","), "comma separated list of HUD widgets to show ("+strings.Join(hudOrder, ", ")+")")
+	flag.Parse()
+
+	names, err := parseHUD(*hud)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+	settings.HUD = names
+
+	if _, ok := cardStyles[settings.CardStyle]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown card style %q, using emoji\n", settings.CardStyle)
+		settings.CardStyle = "emoji"
+	}
+
+	if settings.Player == "" {
+		settings.Player = defaultPlayer()
+	}
+
+	if settings.TimerPrecision < 0 {
+		settings.TimerPrecision = 0
+	} else if settings.TimerPrecision > 3 {
+		settings.TimerPrecision = 3
+	}
+}
+
+// timerInterval is how often the timer text gets redrawn. Whole seconds only
+// need a couple of updates a second, but fractions need to tick faster.
+func timerInterval() time.Duration {
+	if settings.TimerPrecision == 0 {
+		return 500 * time.Millisecond
+	}
+	return 50 * time.Millisecond
+}
+
+// defaultPlayer is the name of the logged in user.
+func defaultPlayer() string {
+	for _, v := range []string{"USER", "USERNAME"} {
+		if s := os.Getenv(v); s != "" {
+			return s
+		}
+	}
+	return "player"
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Boards bigger than this are shrunk down for the card, with each square
+// standing in for a block of tiles.
+const (
+	CARD_MAX_WIDTH  = 16
+	CARD_MAX_HEIGHT = 16
+)
+
+const (
+	CARD_COVERED = iota
+	CARD_OPENED
+	CARD_FLAGGED
+	CARD_EXPLODED
+)
+
+// cardStyles are the squares used for each kind of tile on the card.
+var cardStyles = map[string][]string{
+	"emoji": {"⬛", "🟩", "🚩", "💥"},
+	"text":  {"#", ".", "F", "*"},
+}
+
+// lastCard is the card for the most recent game, which gets written out when
+// bombitron quits.
+var lastCard string
+
+// cardSquare sums up a block of tiles. A mine going off anywhere in it wins
+// out, then the block counts as opened if at least half of it was.
+func (g *Grid) cardSquare(r0, c0, scale int) int {
+	total, opened, flagged := 0, 0, 0
+	for r := r0; r < r0+scale && r < g.Height; r++ {
+		for c := c0; c < c0+scale && c < g.Width; c++ {
+			t := g.Tiles[r*g.Width+c]
+			switch {
+			case t.Exploded:
+				return CARD_EXPLODED
+			case t.HaveFlag:
+				flagged++
+			case !t.Covered:
+				opened++
+			}
+			total++
+		}
+	}
+	if opened*2 >= total {
+		return CARD_OPENED
+	} else if flagged > 0 {
+		return CARD_FLAGGED
+	}
+	return CARD_COVERED
+}
+
+// ShareCard draws the finished board as a small grid of squares for pasting
+// into chat, along with the result and the code to play the same board.
+func ShareCard(g *Grid, style string) string {
+	squares, ok := cardStyles[style]
+	if !ok {
+		squares = cardStyles["emoji"]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "bombitron %s %dx%d, %d mines\n", g.Difficulty, g.Width, g.Height, g.TotalBombs)
+	if g.Daily != "" {
+		fmt.Fprintf(&b, "daily %s\n", g.Daily)
+	}
+	if r := g.Result; r != nil {
+		elapsed := formatElapsed(r.Elapsed, 2)
+		if r.Won {
+			fmt.Fprintf(&b, "won in %ss", elapsed)
+		} else {
+			fmt.Fprintf(&b, "lost in %ss, %d%% cleared", elapsed, g.Cleared())
+		}
+		if r.Assisted {
+			b.WriteString(" (assisted)")
+		}
+		if r.Hints == 1 {
+			b.WriteString(" with 1 hint")
+		} else if r.Hints > 1 {
+			fmt.Fprintf(&b, " with %d hints", r.Hints)
+		}
+		b.WriteString("\n")
+	}
+
+	scale := 1
+	for (g.Width+scale-1)/scale > CARD_MAX_WIDTH || (g.Height+scale-1)/scale > CARD_MAX_HEIGHT {
+		scale++
+	}
+	for r := 0; r < g.Height; r += scale {
+		for c := 0; c < g.Width; c += scale {
+			b.WriteString(squares[g.cardSquare(r, c, scale)])
+		}
+		b.WriteString("\n")
+	}
+	if scale > 1 {
+		fmt.Fprintf(&b, "each square is %dx%d tiles\n", scale, scale)
+	}
+
+	if gc, err := NewGameCode(g); err == nil {
+		fmt.Fprintf(&b, "code %s\n", gc)
+	}
+	return b.String()
+}
+
+// recordCard keeps the card for the game which just finished.
+func (g *Grid) recordCard() {
+	if g.Result == nil || replayViewer != nil {
+		return
+	}
+	lastCard = ShareCard(g, settings.CardStyle)
+}
+
+// writeCard puts the card for the last game wherever -card says to, once the
+// terminal has been put back.
+func writeCard() {
+	if settings.Card == "" || lastCard == "" {
+		return
+	}
+	if settings.Card == "-" {
+		fmt.Print(lastCard)
+		return
+	}
+	if err := ioutil.WriteFile(settings.Card, []byte(lastCard), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't write the result card: %v\n", err)
+	}
+}
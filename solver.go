@@ -0,0 +1,260 @@
+package main
+
+// The solver only knows what the player can see: which tiles are open and
+// the numbers on them, plus the number of mines on the board. It works out
+// which covered tiles must be safe and which must be mines, without ever
+// guessing.
+
+// The rules the solver knows, from simplest to hardest.
+const (
+	RULE_SATISFIED = iota
+	RULE_FULL
+	RULE_PAIR
+	RULE_COUNT
+)
+
+// A Deduction is a tile the solver is sure about, the rule which showed it
+// and the numbers the rule was applied to.
+type Deduction struct {
+	Pos  int
+	Mine bool
+	Rule int
+	From []int
+}
+
+// Known tiles are mines the solver is sure of, and Safe tiles are covered
+// tiles it's sure aren't mines.
+type Solver struct {
+	Width  int
+	Height int
+	Mines  int
+	Open   []bool
+	Count  []int
+	Known  []bool
+	Safe   []bool
+}
+
+// constraint is a number on the board with the covered tiles around it that
+// aren't known to be mines yet, and how many mines are still among them.
+type constraint struct {
+	pos   int
+	tiles []int
+	mines int
+}
+
+func NewSolver(w, h, mines int) *Solver {
+	return &Solver{
+		Width:  w,
+		Height: h,
+		Mines:  mines,
+		Open:   make([]bool, w*h),
+		Count:  make([]int, w*h),
+		Known:  make([]bool, w*h),
+		Safe:   make([]bool, w*h),
+	}
+}
+
+// NewGridSolver sets up a solver with what can be seen of a game. Flags are
+// left out, since they might be wrong.
+func NewGridSolver(g *Grid) *Solver {
+	s := NewSolver(g.Width, g.Height, g.TotalBombs)
+	for cnt, t := range g.Tiles {
+		if !t.Covered {
+			s.Open[cnt] = true
+			s.Count[cnt] = t.BombCount
+		}
+	}
+	return s
+}
+
+func neighbours(w, h, pos int) []int {
+	r, c := pos/w, pos%w
+	var n []int
+	for dr := -1; dr <= 1; dr++ {
+		for dc := -1; dc <= 1; dc++ {
+			nr, nc := r+dr, c+dc
+			if (dr != 0 || dc != 0) && nr >= 0 && nc >= 0 && nr < h && nc < w {
+				n = append(n, nr*w+nc)
+			}
+		}
+	}
+	return n
+}
+
+func (s *Solver) constraints() []constraint {
+	var cs []constraint
+	for pos, open := range s.Open {
+		if !open || s.Count[pos] == 0 {
+			continue
+		}
+		c := constraint{pos: pos, mines: s.Count[pos]}
+		for _, n := range neighbours(s.Width, s.Height, pos) {
+			if s.Known[n] {
+				c.mines--
+			} else if !s.Open[n] && !s.Safe[n] {
+				c.tiles = append(c.tiles, n)
+			}
+		}
+		if len(c.tiles) > 0 {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// subset returns the tiles of b which aren't in a, if all of a is in b.
+func subset(a, b []int) ([]int, bool) {
+	in := map[int]bool{}
+	for _, t := range b {
+		in[t] = true
+	}
+	for _, t := range a {
+		if !in[t] {
+			return nil, false
+		}
+		delete(in, t)
+	}
+	rest := []int{}
+	for _, t := range b {
+		if in[t] {
+			rest = append(rest, t)
+		}
+	}
+	return rest, true
+}
+
+// Step finds every tile which can be worked out from what's known right now.
+// Numbers are looked at on their own first, then in pairs where one number's
+// covered tiles are all next to the other, and finally against the number of
+// mines left on the board.
+func (s *Solver) Step() []Deduction {
+	found := map[int]bool{}
+	var ds []Deduction
+	add := func(tiles []int, mine bool, rule int, from ...int) {
+		for _, t := range tiles {
+			if !found[t] {
+				found[t] = true
+				ds = append(ds, Deduction{Pos: t, Mine: mine, Rule: rule, From: from})
+			}
+		}
+	}
+
+	cs := s.constraints()
+	for _, c := range cs {
+		if c.mines == 0 {
+			add(c.tiles, false, RULE_SATISFIED, c.pos)
+		} else if c.mines == len(c.tiles) {
+			add(c.tiles, true, RULE_FULL, c.pos)
+		}
+	}
+	if len(ds) > 0 {
+		return ds
+	}
+
+	for _, a := range cs {
+		for _, b := range cs {
+			if a.pos == b.pos || len(a.tiles) >= len(b.tiles) {
+				continue
+			}
+			rest, ok := subset(a.tiles, b.tiles)
+			if !ok {
+				continue
+			}
+			if m := b.mines - a.mines; m == 0 {
+				add(rest, false, RULE_PAIR, a.pos, b.pos)
+			} else if m == len(rest) {
+				add(rest, true, RULE_PAIR, a.pos, b.pos)
+			}
+		}
+	}
+	if len(ds) > 0 {
+		return ds
+	}
+
+	left, unknown := s.Mines, []int{}
+	for pos := range s.Open {
+		if s.Known[pos] {
+			left--
+		} else if !s.Open[pos] && !s.Safe[pos] {
+			unknown = append(unknown, pos)
+		}
+	}
+	if left == 0 {
+		add(unknown, false, RULE_COUNT)
+	} else if left == len(unknown) {
+		add(unknown, true, RULE_COUNT)
+	}
+	return ds
+}
+
+// Forced finds every covered tile which can be proved safe without opening
+// anything, going on from each mine and safe tile it finds until it's stuck.
+func (s *Solver) Forced() []int {
+	var safe []int
+	for {
+		ds := s.Step()
+		if len(ds) == 0 {
+			return safe
+		}
+		for _, d := range ds {
+			if d.Mine {
+				s.Known[d.Pos] = true
+			} else {
+				s.Safe[d.Pos] = true
+				safe = append(safe, d.Pos)
+			}
+		}
+	}
+}
+
+// Solved is whether every safe tile is open.
+func (s *Solver) Solved() bool {
+	for pos, open := range s.Open {
+		if !open && !s.Known[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+// open uncovers a tile on a board whose mines are known, spreading out
+// across any opening the way the game does.
+func (s *Solver) open(mines []bool, pos int) {
+	queue := []int{pos}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if s.Open[p] || mines[p] {
+			continue
+		}
+		s.Open[p] = true
+		s.Count[p] = 0
+		for _, n := range neighbours(s.Width, s.Height, p) {
+			if mines[n] {
+				s.Count[p]++
+			}
+		}
+		if s.Count[p] == 0 {
+			queue = append(queue, neighbours(s.Width, s.Height, p)...)
+		}
+	}
+}
+
+// PlayOut keeps going on a board whose mines are known until it's solved or
+// the solver gets stuck, and reports whether it was solved.
+func (s *Solver) PlayOut(mines []bool) bool {
+	for !s.Solved() {
+		ds := s.Step()
+		if len(ds) == 0 {
+			return false
+		}
+		for _, d := range ds {
+			if d.Mine {
+				s.Known[d.Pos] = true
+			} else {
+				s.open(mines, d.Pos)
+			}
+		}
+	}
+	return true
+}
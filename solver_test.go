@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestSolverStep(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		want []Deduction
+	}{
+		{"full number", []string{"*.", ".."}, []Deduction{
+			{Pos: 0, Mine: true, Rule: RULE_FULL},
+		}},
+		{"pair of numbers", []string{"#*#", "..."}, []Deduction{
+			{Pos: 0, Rule: RULE_PAIR},
+			{Pos: 2, Rule: RULE_PAIR},
+		}},
+		{"stuck", []string{"*#", ".."}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := NewGridSolver(testGrid(tt.rows...)).Step()
+			sort.Slice(ds, func(i, j int) bool { return ds[i].Pos < ds[j].Pos })
+			if len(ds) != len(tt.want) {
+				t.Fatalf("Step() = %+v, want %+v", ds, tt.want)
+			}
+			for cnt, d := range ds {
+				w := tt.want[cnt]
+				if d.Pos != w.Pos || d.Mine != w.Mine || d.Rule != w.Rule || len(d.From) == 0 && d.Rule != RULE_COUNT {
+					t.Errorf("deduction %d = %+v, want %+v", cnt, d, w)
+				}
+			}
+		})
+	}
+}
+
+func TestSolverForced(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		safe []int
+	}{
+		{"nothing to find", []string{"*#", ".."}, nil},
+		{"a mine and then the tiles it satisfies", []string{"*.#", "..#"}, []int{2, 5}},
+		{"mine count clears the rest", []string{"..*##"}, []int{3, 4}},
+		{"pair", []string{"#*#", "..."}, []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewGridSolver(testGrid(tt.rows...))
+			safe := s.Forced()
+			sort.Ints(safe)
+			if len(safe) != len(tt.safe) {
+				t.Fatalf("Forced() = %v, want %v", safe, tt.safe)
+			}
+			for cnt, p := range safe {
+				if p != tt.safe[cnt] || !s.Safe[p] {
+					t.Errorf("Forced() = %v, want %v", safe, tt.safe)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSolverPlayOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   []string
+		solved bool
+	}{
+		{"pair", []string{"#*#", "..."}, true},
+		{"one opening", []string{"####", "####", "###*"}, false},
+		{"coin flip", []string{"*#", ".."}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrid(tt.rows...)
+			mines := make([]bool, len(g.Tiles))
+			for cnt, tl := range g.Tiles {
+				mines[cnt] = tl.HaveBomb
+			}
+			if got := NewGridSolver(g).PlayOut(mines); got != tt.solved {
+				t.Errorf("PlayOut() = %v, want %v", got, tt.solved)
+			}
+		})
+	}
+}
+
+func TestProbabilities(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  []string
+		mines int
+		known []int
+		want  []float64
+	}{
+		{"coin flip", []string{"*#", ".."}, 1, nil, []float64{0.5, 0.5, -1, -1}},
+		{"full number", []string{"*.", ".."}, 1, nil, []float64{1, -1, -1, -1}},
+		{"rest of the board is safe", []string{"*.###"}, 1, nil, []float64{0.5, -1, 0.5, 0, 0}},
+		{"one mine off the numbers", []string{"*.###"}, 2, nil, []float64{0.5, -1, 0.5, 0.5, 0.5}},
+		{"known mine", []string{"*.###"}, 2, []int{0}, []float64{1, -1, 0, 0.5, 0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewGridSolver(testGrid(tt.rows...))
+			s.Mines = tt.mines
+			for _, k := range tt.known {
+				s.Known[k] = true
+			}
+			p := s.Probabilities()
+			for cnt, w := range tt.want {
+				if math.Abs(p[cnt]-w) > 1e-9 {
+					t.Errorf("Probabilities() = %v, want %v", p, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestProbabilitiesAddUp(t *testing.T) {
+	// the chances on a board should add up to the number of mines
+	s := NewGridSolver(testGrid(
+		"##*####*##",
+		"#*..**..##",
+		"##.....*##",
+		"##*....###",
+		"#######*##",
+	))
+	sum := 0.0
+	for _, p := range s.Probabilities() {
+		if p >= 0 {
+			sum += p
+		}
+	}
+	if math.Abs(sum-float64(s.Mines)) > 1e-6 {
+		t.Errorf("chances add up to %v, want %d", sum, s.Mines)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	// a known mine where the number says there can't be one, like a wrong
+	// flag, still gives chances between 0 and 1
+	s := NewGridSolver(testGrid("*.#", "..#"))
+	s.Known[2] = true
+	for cnt, p := range s.Estimate() {
+		if s.Open[cnt] {
+			if p != -1 {
+				t.Errorf("open tile %d = %v, want -1", cnt, p)
+			}
+		} else if p < 0 || p > 1 {
+			t.Errorf("tile %d = %v, want a chance between 0 and 1", cnt, p)
+		}
+	}
+}
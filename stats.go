@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// HighScoreTable is one of the high score tables, as written out by
+// `stats --json`.
+type HighScoreTable struct {
+	Difficulty string  `json:"difficulty"`
+	Size       string  `json:"size"`
+	Hinted     bool    `json:"hinted,omitempty"`
+	Scores     []Score `json:"scores"`
+}
+
+type StatsReport struct {
+	Lifetime   []LifetimeStats  `json:"lifetime"`
+	HighScores []HighScoreTable `json:"high_scores"`
+}
+
+func NewStatsReport(scores []Score) StatsReport {
+	r := StatsReport{
+		Lifetime:   []LifetimeStats{},
+		HighScores: []HighScoreTable{},
+	}
+	for _, d := range difficulties {
+		r.Lifetime = append(r.Lifetime, NewLifetimeStats(scores, d))
+		for _, c := range sizeCategories {
+			for _, hinted := range []bool{false, true} {
+				if best := HighScores(scores, d, c, hinted); len(best) > 0 {
+					r.HighScores = append(r.HighScores, HighScoreTable{Difficulty: d, Size: c, Hinted: hinted, Scores: best})
+				}
+			}
+		}
+	}
+	return r
+}
+
+// runStats prints the lifetime stats and the high score tables for the
+// stats command.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "write the stats out as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: bombitron stats [-json]")
+		return 1
+	}
+
+	scores, err := loadScores()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r := NewStatsReport(scores)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tplayed\twon\tstreak\tbest streak\taverage\tmedian\tfirst move losses\tguess losses\thinted games\thints")
+	for _, ls := range r.Lifetime {
+		fmt.Fprintf(w, "%s\t%d\t%.0f%%\t%d\t%d\t%s\t%s\t%d\t%d\t%d\t%d\n", ls.Difficulty, ls.Played, ls.WinRate,
+			ls.CurrentStreak, ls.BestStreak, msText(ls.AverageMs), msText(ls.MedianMs), ls.FirstMoveLosses, ls.LateGuessLosses,
+			ls.HintedGames, ls.Hints)
+	}
+	w.Flush()
+
+	for _, t := range r.HighScores {
+		if t.Hinted {
+			fmt.Printf("\n%s, %s boards, with hints\n", t.Difficulty, t.Size)
+		} else {
+			fmt.Printf("\n%s, %s boards\n", t.Difficulty, t.Size)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\ttime\tplayer\tboard\t3bv\teff.\tdate")
+		for cnt, s := range t.Scores {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%dx%d/%d\t%d\t%d%%\t%s\n", cnt+1, formatElapsed(s.Elapsed(), 3), s.Player,
+				s.Width, s.Height, s.Mines, s.BBBV, s.Efficiency, s.Date.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	}
+	return 0
+}
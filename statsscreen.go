@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	sprite "github.com/pdevine/go-asciisprite"
+	tm "github.com/pdevine/go-asciisprite/termbox"
+)
+
+const (
+	HISTOGRAM_HEIGHT = 16
+	HISTOGRAM_BAR    = 6
+)
+
+// StatsScreen shows the lifetime stats for one difficulty at a time over the
+// title screen, with a histogram of how long the wins took.
+type StatsScreen struct {
+	sprite.BaseSprite
+	font       *sprite.Font
+	Scores     []Score
+	Difficulty int
+}
+
+func NewStatsScreen() *StatsScreen {
+	s := &StatsScreen{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	s.Init()
+
+	s.RegisterEvent("resizeScreen", func() {
+		if s.Visible {
+			s.draw()
+		}
+	})
+
+	return s
+}
+
+func (s *StatsScreen) Open() {
+	s.Scores, _ = loadScores()
+	s.draw()
+	s.Visible = true
+	allSprites.MoveToTop(s)
+}
+
+func (s *StatsScreen) Close() {
+	s.Visible = false
+}
+
+func (s *StatsScreen) HandleKey(ev tm.Event) {
+	switch ev.Key {
+	case tm.KeyArrowLeft:
+		s.Difficulty = (s.Difficulty + len(difficulties) - 1) % len(difficulties)
+	case tm.KeyArrowRight:
+		s.Difficulty = (s.Difficulty + 1) % len(difficulties)
+	default:
+		s.Close()
+		return
+	}
+	s.draw()
+}
+
+func msText(ms int64) string {
+	return formatElapsed(time.Duration(ms)*time.Millisecond, 2)
+}
+
+func (s *StatsScreen) lines(ls LifetimeStats) []string {
+	if ls.Played == 0 {
+		return []string{"no games yet"}
+	}
+	lines := []string{
+		fmt.Sprintf("played %d", ls.Played),
+		fmt.Sprintf("won %d - %.0f%%", ls.Won, ls.WinRate),
+		fmt.Sprintf("streak %d best %d", ls.CurrentStreak, ls.BestStreak),
+	}
+	if ls.Won > 0 {
+		lines = append(lines,
+			fmt.Sprintf("average %s", msText(ls.AverageMs)),
+			fmt.Sprintf("median %s", msText(ls.MedianMs)),
+		)
+	}
+	if lost := ls.Played - ls.Won; lost > 0 {
+		lines = append(lines,
+			fmt.Sprintf("lost on first move %d", ls.FirstMoveLosses),
+			fmt.Sprintf("lost on a guess %d", ls.LateGuessLosses),
+		)
+	}
+	if ls.Hints > 0 {
+		lines = append(lines, fmt.Sprintf("hints %d in %d games", ls.Hints, ls.HintedGames))
+	}
+	return lines
+}
+
+// histogramSurface draws a bar for each bucket, scaled to the fullest one,
+// with the fastest and slowest times underneath.
+func (s *StatsScreen) histogramSurface(h []HistogramBucket) sprite.Surface {
+	most := 0
+	for _, b := range h {
+		if b.Count > most {
+			most = b.Count
+		}
+	}
+
+	w := len(h) * HISTOGRAM_BAR
+	surf := sprite.NewSurface(w, HISTOGRAM_HEIGHT+2+LINE_HEIGHT, true)
+	for cnt, b := range h {
+		bar := b.Count * HISTOGRAM_HEIGHT / most
+		if b.Count > 0 && bar == 0 {
+			bar = 1
+		}
+		for y := HISTOGRAM_HEIGHT - bar; y < HISTOGRAM_HEIGHT; y++ {
+			for x := cnt * HISTOGRAM_BAR; x < (cnt+1)*HISTOGRAM_BAR-1; x++ {
+				surf.Blocks[y][x] = 'b'
+			}
+		}
+	}
+	for x := 0; x < w; x++ {
+		surf.Blocks[HISTOGRAM_HEIGHT][x] = 'G'
+	}
+
+	lo := textSurface(s.font, msText(h[0].MinMs), 'X')
+	hi := textSurface(s.font, msText(h[len(h)-1].MaxMs), 'X')
+	surf.Blit(lo, 1, HISTOGRAM_HEIGHT+1)
+	if hi.Width+lo.Width+4 <= w {
+		surf.Blit(hi, w-hi.Width, HISTOGRAM_HEIGHT+1)
+	}
+	return surf
+}
+
+func (s *StatsScreen) draw() {
+	d := difficulties[s.Difficulty]
+	ls := NewLifetimeStats(s.Scores, d)
+	title := fmt.Sprintf("%s stats", d)
+	lines := s.lines(ls)
+
+	w := len(title) * 4
+	for _, l := range lines {
+		if len(l)*4 > w {
+			w = len(l) * 4
+		}
+	}
+	h := LINE_HEIGHT + 2 + len(lines)*LINE_HEIGHT
+
+	var hist sprite.Surface
+	if len(ls.Histogram) > 0 {
+		hist = s.histogramSurface(ls.Histogram)
+		if hist.Width > w {
+			w = hist.Width
+		}
+		h += 2 + hist.Height
+	}
+
+	surf := panelSurface(w, h)
+	t := textSurface(s.font, title, 'b')
+	surf.Blit(t, surf.Width/2-t.Width/2, SUMMARY_PAD)
+	y := SUMMARY_PAD + LINE_HEIGHT + 2
+	for _, l := range lines {
+		surf.Blit(textSurface(s.font, l, 'X'), SUMMARY_PAD, y)
+		y += LINE_HEIGHT
+	}
+	if len(ls.Histogram) > 0 {
+		surf.Blit(hist, SUMMARY_PAD, y+2)
+	}
+
+	s.BlockCostumes = []*sprite.Surface{&surf}
+	s.SetCostume(0)
+	s.X = Width/2 - surf.Width/2
+	s.Y = Height/2 - surf.Height/2
+	if s.Y < 0 {
+		s.Y = 0
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/rand"
+
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+const (
+	FACE_NORMAL = iota
+	FACE_OOH
+	FACE_DEAD
+	FACE_COOL
+)
+
+// StatusFace sits in the middle of the header and shows how the game is
+// going. Clicking on it starts a new game.
+type StatusFace struct {
+	sprite.BaseSprite
+	Pressed bool
+}
+
+func NewStatusFace() *StatusFace {
+	f := &StatusFace{BaseSprite: sprite.BaseSprite{
+		Y:       0,
+		Visible: false},
+	}
+	f.Init()
+
+	for _, s := range []string{faceNormal, faceOoh, faceDead, faceCool} {
+		surf := sprite.NewSurfaceFromString(s, true)
+		f.BlockCostumes = append(f.BlockCostumes, &surf)
+	}
+	f.SetCostume(FACE_NORMAL)
+	f.X = Width/2 - f.BlockCostumes[0].Width/2
+
+	f.RegisterEvent("resizeScreen", func() {
+		f.X = Width/2 - f.BlockCostumes[0].Width/2
+	})
+
+	f.RegisterEvent("SelectorClicked", func() {
+		f.Visible = boardEditor == nil
+	})
+
+	f.RegisterEvent("PressTile", func() {
+		if gameGrid.State != GAME_OVER {
+			f.SetCostume(FACE_OOH)
+		}
+	})
+
+	f.RegisterEvent("ReleaseTile", func() {
+		if gameGrid.State != GAME_OVER {
+			f.SetCostume(FACE_NORMAL)
+		}
+	})
+
+	f.RegisterEvent("Explode", func() {
+		f.SetCostume(FACE_DEAD)
+	})
+
+	f.RegisterEvent("GameWon", func() {
+		f.SetCostume(FACE_COOL)
+	})
+
+	f.RegisterEvent("Undo", func() {
+		f.SetCostume(FACE_NORMAL)
+	})
+
+	f.RegisterEvent("NewGame", func() {
+		f.SetCostume(FACE_NORMAL)
+	})
+
+	return f
+}
+
+// NewGame throws away the current board and starts another one of the same
+// size and difficulty. On the daily board, a puzzle, a game code or a board
+// file it has another go at the same one.
+func (g *Grid) NewGame() {
+	if g.State == GAME_RUNNING {
+		g.SaveReplay()
+	}
+	if g.State == GAME_STARTED && g.Layout != nil {
+		// the board hasn't been touched yet, so there's nothing to throw away
+		return
+	} else if g.Daily != "" || g.Puzzle != "" || g.Code || g.Difficulty == "custom" {
+		g.Retry()
+		return
+	}
+	g.Reset()
+	g.Seed = rand.Int63()
+	g.Record = settings.RecordReplays
+	g.State = GAME_STARTED
+}
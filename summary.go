@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	sprite "github.com/pdevine/go-asciisprite"
+)
+
+const (
+	SUMMARY_PAD = 4
+	SUMMARY_GAP = 8
+	LINE_HEIGHT = 7
+)
+
+// A summaryButton is a key on the summary panel which can also be clicked.
+// Its box is relative to the panel.
+type summaryButton struct {
+	Key    rune
+	Label  string
+	x0, y0 int
+	x1, y1 int
+}
+
+// Summary is the panel which comes down at the end of a game with how it
+// went, and the choice of what to do next. After a loss it waits for the
+// explosion to finish first.
+type Summary struct {
+	sprite.BaseSprite
+	font       *sprite.Font
+	TargetY    int
+	VY         float64
+	Wait       int
+	Pending    bool
+	Saved      string
+	Exported   string
+	Copied     string
+	CardCopied string
+	Code       string
+	buttons    []summaryButton
+}
+
+func NewSummary() *Summary {
+	s := &Summary{BaseSprite: sprite.BaseSprite{
+		Visible: false},
+		font: sprite.NewPakuFont(),
+	}
+	s.Init()
+
+	s.RegisterEvent("GameOver", func() {
+		if replayViewer != nil {
+			return
+		}
+		s.Saved = ""
+		s.Exported = ""
+		s.Copied = ""
+		s.CardCopied = ""
+		s.Code = ""
+		if gc, err := NewGameCode(gameGrid); err == nil {
+			s.Code = gc.String()
+		}
+		s.Wait = 0
+		if !gameGrid.Result.Won {
+			s.Wait = 15
+			if animate() {
+				s.Wait = gameGrid.Kaboom.TimeOutVis
+			}
+		}
+		s.Pending = true
+	})
+
+	s.RegisterEvent("resizeScreen", func() {
+		if s.Visible {
+			s.draw()
+			s.Y = s.TargetY
+		}
+	})
+
+	for _, e := range []string{"NewGame", "Undo", "ReturnToGrid"} {
+		s.RegisterEvent(e, func() {
+			s.Visible = false
+			s.Pending = false
+		})
+	}
+
+	return s
+}
+
+// textSurface writes out a line of text in the given colour.
+func textSurface(f *sprite.Font, s string, c rune) sprite.Surface {
+	return sprite.NewSurfaceFromString(strings.Replace(f.BuildString(s), "X", string(c), -1), true)
+}
+
+// panelSurface makes a bordered panel with room for w by h blocks inside the
+// padding.
+func panelSurface(w, h int) sprite.Surface {
+	surf := sprite.NewSurface(w+2*SUMMARY_PAD, h+2*SUMMARY_PAD, false)
+	for y := range surf.Blocks {
+		for x := range surf.Blocks[y] {
+			surf.Blocks[y][x] = 'l'
+		}
+	}
+	surf.Rectangle(0, 0, surf.Width-1, surf.Height-1, 'X')
+	return surf
+}
+
+// stats lists the figures shown on the panel for the game which just ended.
+func (s *Summary) stats() []string {
+	g := gameGrid
+	r := g.Result
+	total, solved := g.BoardValue()
+	left, right, chord := g.ClickCounts()
+
+	lines := []string{
+		fmt.Sprintf("time %s", formatElapsed(r.Elapsed, settings.TimerPrecision)),
+		fmt.Sprintf("3bv %d/%d", solved, total),
+		fmt.Sprintf("3bv/s %.2f", g.Speed()),
+		fmt.Sprintf("clicks %d", left+right+chord),
+		fmt.Sprintf("left %d right %d", left, right),
+		fmt.Sprintf("chord %d", chord),
+		fmt.Sprintf("efficiency %d%%", g.Efficiency()),
+	}
+	if !r.Won {
+		lines = append(lines, fmt.Sprintf("cleared %d%%", g.Cleared()))
+	}
+	if r.NewBest {
+		lines = append(lines, "new best!")
+	} else if r.Best > 0 {
+		lines = append(lines, fmt.Sprintf("best %s", formatElapsed(r.Best, settings.TimerPrecision)))
+	}
+	for _, what := range r.Unsaved {
+		lines = append(lines, what+" not saved")
+	}
+	if r.Assisted {
+		lines = append(lines, "assisted")
+	}
+	if r.Hints > 0 {
+		lines = append(lines, fmt.Sprintf("hints %d", r.Hints))
+	}
+	lines = append(lines, g.guessLines()...)
+	if g.Daily != "" {
+		lines = append(lines, "daily "+g.Daily)
+	}
+	if g.Puzzle != "" {
+		if p := findPuzzle(g.Puzzle); p != nil {
+			lines = append(lines, "puzzle "+p.Name)
+		}
+	}
+	return lines
+}
+
+// codeLines splits the game code over as many lines as it takes to fit in
+// width blocks.
+func codeLines(code string, width int) []string {
+	n := width / 4
+	if n < 1 {
+		n = 1
+	}
+	lines := []string{}
+	for len(code) > n {
+		lines = append(lines, code[:n])
+		code = code[n:]
+	}
+	return append(lines, code)
+}
+
+// draw lays the panel out in as many columns as will fit on the screen.
+func (s *Summary) draw() {
+	g := gameGrid
+	if g.Result == nil {
+		return
+	}
+
+	title := "boom!"
+	titleColour := 'r'
+	if g.Result.Won {
+		title = "you won!"
+		titleColour = 'b'
+	}
+
+	lines := s.stats()
+	colWidth := 0
+	for _, l := range lines {
+		if len(l)*4 > colWidth {
+			colWidth = len(l) * 4
+		}
+	}
+	cols := (Width - 2*SUMMARY_PAD - 8 + SUMMARY_GAP) / (colWidth + SUMMARY_GAP)
+	if cols > 2 {
+		cols = 2
+	} else if cols < 1 {
+		cols = 1
+	}
+	rows := (len(lines) + cols - 1) / cols
+
+	labels := []summaryButton{
+		{Key: 'r', Label: "r retry"},
+		{Key: 'n', Label: "n new game"},
+		{Key: 's', Label: "s save replay"},
+	}
+	if s.Saved != "" {
+		labels[2].Label = s.Saved
+	}
+	label := "x export board"
+	if s.Exported != "" {
+		label = s.Exported
+	}
+	labels = append(labels, summaryButton{Key: 'x', Label: label})
+	if s.Code != "" {
+		label := "c copy code"
+		if s.Copied != "" {
+			label = s.Copied
+		}
+		labels = append(labels, summaryButton{Key: 'c', Label: label})
+	}
+	label = "k copy card"
+	if s.CardCopied != "" {
+		label = s.CardCopied
+	}
+	labels = append(labels, summaryButton{Key: 'k', Label: label})
+
+	w := cols*(colWidth+SUMMARY_GAP) - SUMMARY_GAP
+	buttonsWidth := 0
+	for _, b := range labels {
+		buttonsWidth += len(b.Label)*4 + 4 + SUMMARY_GAP
+	}
+	buttonsWidth -= SUMMARY_GAP
+	stacked := buttonsWidth > Width-2*SUMMARY_PAD-8
+	if !stacked && buttonsWidth > w {
+		w = buttonsWidth
+	}
+	if len(title)*4 > w {
+		w = len(title) * 4
+	}
+
+	// the code goes underneath the rest, on one line if there's room
+	var code []string
+	if s.Code != "" {
+		avail := Width - 2*SUMMARY_PAD - 8
+		if w > avail {
+			avail = w
+		}
+		code = codeLines("code "+s.Code, avail)
+		if len(code) > 1 {
+			code = append([]string{"code"}, codeLines(s.Code, avail)...)
+		}
+		for _, l := range code {
+			if len(l)*4 > w {
+				w = len(l) * 4
+			}
+		}
+	}
+
+	buttonRows := 1
+	if stacked {
+		buttonRows = len(labels)
+		for _, b := range labels {
+			if len(b.Label)*4+4 > w {
+				w = len(b.Label)*4 + 4
+			}
+		}
+	}
+
+	h := LINE_HEIGHT + 2 + (rows+len(code))*LINE_HEIGHT + 2 + buttonRows*(LINE_HEIGHT+3)
+	surf := panelSurface(w, h)
+
+	y := SUMMARY_PAD
+	t := textSurface(s.font, title, titleColour)
+	surf.Blit(t, surf.Width/2-t.Width/2, y)
+	y += LINE_HEIGHT + 2
+
+	for cnt, l := range lines {
+		c, r := cnt/rows, cnt%rows
+		surf.Blit(textSurface(s.font, l, 'X'), SUMMARY_PAD+c*(colWidth+SUMMARY_GAP), y+r*LINE_HEIGHT)
+	}
+	y += rows * LINE_HEIGHT
+	for _, l := range code {
+		surf.Blit(textSurface(s.font, l, 'b'), SUMMARY_PAD, y)
+		y += LINE_HEIGHT
+	}
+	y += 2
+
+	s.buttons = nil
+	x := SUMMARY_PAD
+	for _, b := range labels {
+		b.x0, b.y0 = x, y
+		b.x1, b.y1 = x+len(b.Label)*4+3, y+LINE_HEIGHT+1
+		surf.Rectangle(b.x0, b.y0, b.x1, b.y1, 'G')
+		surf.Blit(textSurface(s.font, b.Label, 'X'), b.x0+2, b.y0+1)
+		s.buttons = append(s.buttons, b)
+
+		if stacked {
+			y += LINE_HEIGHT + 3
+		} else {
+			x = b.x1 + 1 + SUMMARY_GAP
+		}
+	}
+
+	s.BlockCostumes = []*sprite.Surface{&surf}
+	s.SetCostume(0)
+	s.X = Width/2 - surf.Width/2
+	s.TargetY = Height/2 - surf.Height/2
+	if s.TargetY < 0 {
+		s.TargetY = 0
+	}
+}
+
+func (s *Summary) Update() {
+	if r := gameGrid.Result; r != nil && r.checkSaved() && s.Visible {
+		s.draw()
+	}
+	if s.Pending {
+		if s.Wait > 0 {
+			s.Wait--
+			return
+		}
+		s.Pending = false
+		s.draw()
+		s.Y = -len(s.BlockCostumes[0].Blocks)
+		s.Visible = true
+		gameGrid.Kaboom.Visible = false
+		allSprites.MoveToTop(s)
+
+		if s.Code != "" {
+			setExitMessage("code", "game code: "+s.Code)
+			if settings.Clipboard {
+				s.HandleKey('c')
+			}
+		}
+	}
+
+	if !s.Visible || s.Y == s.TargetY {
+		return
+	}
+	if !animate() {
+		s.Y = s.TargetY
+		return
+	}
+
+	s.VY = (float64(s.TargetY) - float64(s.Y)) * 0.3
+	s.Y += int(math.Round(s.VY))
+}
+
+// Click presses whichever button is under the mouse.
+func (s *Summary) Click(x, y int) bool {
+	for _, b := range s.buttons {
+		if x >= s.X+b.x0 && x <= s.X+b.x1 && y >= s.Y+b.y0 && y <= s.Y+b.y1 {
+			return s.HandleKey(b.Key)
+		}
+	}
+	return false
+}
+
+// HandleKey carries out one of the panel's choices.
+func (s *Summary) HandleKey(ch rune) bool {
+	g := gameGrid
+	switch ch {
+	case 'r':
+		pointer = Pointer{}
+		g.Retry()
+	case 'n':
+		pointer = Pointer{}
+		g.NewGame()
+	case 's':
+		g.Record = true
+		fn, err := g.SaveReplay()
+		if err != nil {
+			s.Saved = "replay not saved"
+		} else if fn != "" {
+			s.Saved = "replay saved"
+		}
+		s.draw()
+	case 'x':
+		s.Exported = "board exported"
+		if _, err := g.ExportBoard(); err != nil {
+			s.Exported = "board not exported"
+		}
+		s.draw()
+	case 'c':
+		if s.Code == "" {
+			return false
+		}
+		copyToClipboard(s.Code)
+		s.Copied = "code copied on quit"
+		s.CardCopied = ""
+		s.draw()
+	case 'k':
+		copyToClipboard(ShareCard(g, settings.CardStyle))
+		s.CardCopied = "card copied on quit"
+		s.Copied = ""
+		s.draw()
+	default:
+		return false
+	}
+	return true
+}
+
+// Retry plays the same board again. Since the board is known by then, the
+// game is marked as assisted, except on the daily board and puzzles where
+// every attempt is counted instead.
+func (g *Grid) Retry() {
+	if g.Daily != "" {
+		g.StartDaily(g.Difficulty, dailyRate(g.Difficulty))
+		return
+	} else if g.Puzzle != "" {
+		if err := g.StartPuzzle(findPuzzle(g.Puzzle)); err != nil {
+			g.HintBox.Say("couldn't start the puzzle again, " + err.Error())
+		}
+		return
+	}
+
+	mines := []int{}
+	for cnt, t := range g.Tiles {
+		if t.HaveBomb {
+			mines = append(mines, cnt)
+		}
+	}
+	start := g.Start
+	code := g.Code
+
+	g.Reset()
+	g.Record = settings.RecordReplays
+	g.Layout = mines
+	g.Start = start
+	g.Code = code
+	g.Assisted = true
+	g.State = GAME_STARTED
+}
@@ -13,6 +13,11 @@ type TitleOverlay struct {
 	Logo      *TitleLogo
 	Bomb      *TitleBomb
 	Uni       *UniLogo
+	Scores    *ScoreBoard
+	Stats     *StatsScreen
+	Code      *CodeInput
+	Puzzles   *PuzzleList
+	Notice    *Notice
 }
 
 type TitleLogo struct {
@@ -37,12 +42,14 @@ type Selector struct {
 	VX       float64
 	VY       float64
 	BombRate float64
+	Armed    bool
 }
 
 type Spark struct {
 	sprite.BaseSprite
 	Yoffset  int
 	Dead     bool
+	Burst    bool
 	Lifetime int
 	VX       int
 	VY       int
@@ -70,6 +77,23 @@ func (t *TitleOverlay) SetGameReady() {
 		NewSelector("med."),
 		NewSelector("hard"),
 	}
+	// the ones down the right hand side stack up under each other
+	right := []*Selector{}
+	if haveSaveGame() {
+		right = append(right, NewSelector("resume"))
+	}
+	if dailyFits() {
+		right = append(right, NewSelector("daily"))
+	}
+	right = append(right, NewSelector("code"))
+	right = append(right, NewSelector("puzzles"))
+	for cnt, s := range right {
+		s.TargetY = 2 + cnt*12
+	}
+	t.Selectors = append(t.Selectors, right...)
+	t.Selectors = append(t.Selectors, NewSelector("scores"))
+	t.Selectors = append(t.Selectors, NewSelector("stats"))
+	t.Selectors = append(t.Selectors, NewSelector("edit"))
 	t.Logo = NewTitleLogo()
 	t.Bomb = NewTitleBomb()
 	t.Uni = NewUniLogo()
@@ -81,6 +105,28 @@ func (t *TitleOverlay) SetGameReady() {
 	for _, s := range t.Selectors {
 		allSprites.Sprites = append(allSprites.Sprites, s)
 	}
+
+	t.Scores = NewScoreBoard()
+	t.Stats = NewStatsScreen()
+	t.Code = NewCodeInput()
+	t.Puzzles = NewPuzzleList()
+	t.Notice = NewNotice()
+	allSprites.Sprites = append(allSprites.Sprites, t.Scores)
+	allSprites.Sprites = append(allSprites.Sprites, t.Stats)
+	allSprites.Sprites = append(allSprites.Sprites, t.Code)
+	allSprites.Sprites = append(allSprites.Sprites, t.Puzzles)
+	allSprites.Sprites = append(allSprites.Sprites, t.Notice)
+}
+
+// RemoveSelector takes a selector off the title screen.
+func (t *TitleOverlay) RemoveSelector(s *Selector) {
+	for cnt, o := range t.Selectors {
+		if o == s {
+			t.Selectors = append(t.Selectors[:cnt], t.Selectors[cnt+1:]...)
+			break
+		}
+	}
+	allSprites.Remove(s)
 }
 
 func (t *TitleOverlay) MoveToTop() {
@@ -104,6 +150,17 @@ func (t *TitleOverlay) CheckSelectorClicked(x, y int) *Selector {
 	return nil
 }
 
+// Daily is whether the daily selector has been switched on, so that picking
+// a difficulty starts the daily board.
+func (t *TitleOverlay) Daily() bool {
+	for _, s := range t.Selectors {
+		if s.Type == "daily" && s.Armed {
+			return true
+		}
+	}
+	return false
+}
+
 func NewSelector(n string) *Selector {
 	s := &Selector{BaseSprite: sprite.BaseSprite{
 		Y:       Height - 20,
@@ -117,17 +174,21 @@ func NewSelector(n string) *Selector {
 
 	surf1 := sprite.NewSurface(40, 10, false)
 	surf2 := sprite.NewSurface(40, 10, false)
+	surf3 := sprite.NewSurface(40, 10, false)
 	for rcnt, r := range surf1.Blocks {
 		for ccnt, _ := range r {
 			surf1.Blocks[rcnt][ccnt] = 'l'
 			surf2.Blocks[rcnt][ccnt] = 'w'
+			surf3.Blocks[rcnt][ccnt] = 'y'
 		}
 	}
 	surf1.Rectangle(0, 0, 39, 9, 'X')
 	surf2.Rectangle(0, 0, 39, 9, 'X')
+	surf3.Rectangle(0, 0, 39, 9, 'X')
 	surf1.Blit(w, surf1.Width/2-w.Width/2, 2)
 	surf2.Blit(w, surf2.Width/2-w.Width/2, 2)
-	s.BlockCostumes = []*sprite.Surface{&surf1, &surf2}
+	surf3.Blit(w, surf3.Width/2-w.Width/2, 2)
+	s.BlockCostumes = []*sprite.Surface{&surf1, &surf2, &surf3}
 	s.SetCostume(0)
 
 	if n == "easy" {
@@ -145,6 +206,21 @@ func NewSelector(n string) *Selector {
 		s.X = Width
 		s.Y = Height - 20
 		s.BombRate = HARD_BOMB_RATE
+	} else if n == "resume" || n == "daily" || n == "code" || n == "puzzles" {
+		s.X = Width - surf1.Width - 10
+		s.Y = -surf1.Height
+	} else if n == "scores" {
+		s.X = 10
+		s.Y = -surf1.Height
+		s.TargetY = 2
+	} else if n == "stats" {
+		s.X = 10
+		s.Y = -surf1.Height
+		s.TargetY = 14
+	} else if n == "edit" {
+		s.X = 10
+		s.Y = -surf1.Height
+		s.TargetY = 26
 	}
 
 	s.RegisterEvent("SelectorClicked", func() {
@@ -152,7 +228,9 @@ func NewSelector(n string) *Selector {
 	})
 
 	s.RegisterEvent("MouseMove", func() {
-		if MouseX >= s.X && MouseX < s.X+surf1.Width && MouseY >= s.Y && MouseY < s.Y+surf1.Height {
+		if s.Armed {
+			s.SetCostume(2)
+		} else if MouseX >= s.X && MouseX < s.X+surf1.Width && MouseY >= s.Y && MouseY < s.Y+surf1.Height {
 			s.SetCostume(1)
 		} else {
 			s.SetCostume(0)
@@ -167,6 +245,15 @@ func (s *Selector) Update() {
 		return
 	}
 
+	if !animate() {
+		if s.Type == "easy" || s.Type == "hard" {
+			s.X = s.TargetX
+		} else {
+			s.Y = s.TargetY
+		}
+		return
+	}
+
 	if s.Type == "easy" || s.Type == "hard" {
 		if s.TargetX == s.X {
 			return
@@ -234,6 +321,10 @@ func (u *UniLogo) Update() {
 	if u.CurrentCostume == len(u.BlockCostumes)-1 {
 		return
 	}
+	if !animate() {
+		u.SetCostume(len(u.BlockCostumes) - 1)
+		return
+	}
 	if u.Timer >= u.TimeOut {
 		u.NextCostume()
 		u.Timer = 0
@@ -262,9 +353,34 @@ func NewSpark() *Spark {
 	return s
 }
 
+// NewBurstSpark makes a one-off spark which flies out from x, y and then
+// dies instead of starting over like the sparks on the title bomb.
+func NewBurstSpark(x, y int) *Spark {
+	s := NewSpark()
+	s.Burst = true
+	s.X = x
+	s.Y = y
+	s.VX = rand.Intn(5) - 2
+	s.VY = rand.Intn(5) - 3
+	s.Lifetime = rand.Intn(6) + 3
+	return s
+}
+
 func (s *Spark) Update() {
+	if s.Dead {
+		return
+	}
+	if !animate() {
+		s.Visible = false
+		return
+	}
 	s.Lifetime -= 1
 	if s.Lifetime <= 0 {
+		if s.Burst {
+			s.Dead = true
+			s.Visible = false
+			return
+		}
 		s.Reset()
 	}
 	s.X += s.VX
@@ -308,8 +424,12 @@ func (b *TitleBomb) Update() {
 	if b.TargetY == b.Y {
 		return
 	}
-	b.VY = (float64(b.TargetY) - float64(b.Y)) * 0.3
-	b.Y += int(math.Round(b.VY))
+	if animate() {
+		b.VY = (float64(b.TargetY) - float64(b.Y)) * 0.3
+		b.Y += int(math.Round(b.VY))
+	} else {
+		b.Y = b.TargetY
+	}
 
 	for _, s := range b.Sparks {
 		s.Yoffset = b.Y
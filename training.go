@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Guess is a reveal which the numbers didn't prove safe, made while some
+// other tile could have been proved safe. Move is the number of the move in
+// the game, and Safe is the nearest tile which was certain.
+type Guess struct {
+	Move int
+	Pos  int
+	Safe int
+}
+
+// training is whether reveals are being checked for guesses. They always are
+// in the replay viewer, so the guesses can be picked out of a replay.
+func training() bool {
+	return settings.Training || replayViewer != nil
+}
+
+// ToggleTraining turns training mode on or off.
+func (g *Grid) ToggleTraining() {
+	settings.Training = !settings.Training
+	if settings.Training {
+		g.HintBox.Say("training on")
+	} else {
+		g.HintBox.Say("training off")
+	}
+}
+
+// noteGuess is called with the tiles a move is about to open, before any of
+// them are. It asks the solver what could be proved from the board as it
+// stands, and if one of the tiles couldn't be while another one could, the
+// move is noted as a guess and the tile which was certain is pointed out.
+func (g *Grid) noteGuess(tiles []int) {
+	if !training() || g.State != GAME_RUNNING {
+		return
+	}
+
+	var guessed []int
+	for _, pos := range tiles {
+		if t := g.Tiles[pos]; t.Covered && !t.HaveFlag {
+			guessed = append(guessed, pos)
+		}
+	}
+	if len(guessed) == 0 {
+		return
+	}
+
+	safe := NewGridSolver(g).Forced()
+	if len(safe) == 0 {
+		return
+	}
+	forced := map[int]bool{}
+	for _, pos := range safe {
+		forced[pos] = true
+	}
+
+	for _, pos := range guessed {
+		if forced[pos] {
+			continue
+		}
+		gs := Guess{Move: len(g.Moves), Pos: pos, Safe: g.nearest(pos, safe)}
+		g.Guesses = append(g.Guesses, gs)
+		g.showGuess(gs)
+		return
+	}
+}
+
+// showGuess points out the tile which was safe when a guess was made.
+func (g *Grid) showGuess(gs Guess) {
+	g.HintBox.Show(&Hint{
+		Pos:  gs.Safe,
+		Sure: true,
+		Text: fmt.Sprintf("%s was a guess, %s was safe", g.tileName(gs.Pos), g.tileName(gs.Safe)),
+	})
+}
+
+// nearest picks the tile out of a list which is closest to pos.
+func (g *Grid) nearest(pos int, tiles []int) int {
+	best, dist := tiles[0], -1
+	r, c := pos/g.Width, pos%g.Width
+	for _, t := range tiles {
+		dr, dc := t/g.Width-r, t%g.Width-c
+		if dr < 0 {
+			dr = -dr
+		}
+		if dc < 0 {
+			dc = -dc
+		}
+		d := dr
+		if dc > d {
+			d = dc
+		}
+		if dist < 0 || d < dist {
+			best, dist = t, d
+		}
+	}
+	return best
+}
+
+// guessLines lists the guesses for the summary, as many as fit on a line.
+func (g *Grid) guessLines() []string {
+	if len(g.Guesses) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("guesses %d", len(g.Guesses))}
+	at := []string{}
+	for cnt, gs := range g.Guesses {
+		if cnt == 4 {
+			at = append(at, fmt.Sprintf("and %d more", len(g.Guesses)-cnt))
+			break
+		}
+		at = append(at, g.tileName(gs.Pos))
+	}
+	return append(lines, "at "+strings.Join(at, " "))
+}